@@ -11,14 +11,24 @@ import (
 
 	"github.com/Shreehari-Acharya/vayuu/config"
 	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+	"github.com/Shreehari-Acharya/vayuu/internal/channel"
 	"github.com/Shreehari-Acharya/vayuu/internal/prompts"
+	"github.com/Shreehari-Acharya/vayuu/internal/sandbox"
 	"github.com/Shreehari-Acharya/vayuu/internal/telegram"
 	"github.com/Shreehari-Acharya/vayuu/internal/tools"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
 )
 
+// shutdownTimeout bounds how long shutdown waits for the channels to stop.
+const shutdownTimeout = 15 * time.Second
+
 func main() {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})))
 
+	if len(os.Args) > 1 && os.Args[1] == sandbox.InitCommand {
+		os.Exit(sandbox.RunInit(os.Args[2:]))
+	}
+
 	if len(os.Args) > 1 && os.Args[1] == "setup" {
 		if err := config.RunSetup(); err != nil {
 			fmt.Fprintf(os.Stderr, "setup failed: %v\n", err)
@@ -40,7 +50,17 @@ func main() {
 		os.Exit(1)
 	}
 
-	toolEnv, err := tools.NewToolEnv(cfg.AgentWorkDir)
+	sb, err := sandbox.New(cfg.Sandbox)
+	if err != nil {
+		slog.Error("failed to set up command sandbox", "error", err)
+		os.Exit(1)
+	}
+	if err := sb.Check(context.Background(), cfg.AgentWorkDir); err != nil {
+		slog.Error("failed to set up command sandbox", "error", err)
+		os.Exit(1)
+	}
+
+	toolEnv, err := tools.NewToolEnv(cfg.AgentWorkDir, sb)
 	if err != nil {
 		slog.Error("failed to initialize tool environment", "error", err)
 		os.Exit(1)
@@ -51,20 +71,69 @@ func main() {
 		os.Exit(1)
 	}
 
-	bot, err := telegram.NewBot(cfg, agentInstance, toolEnv)
+	registry, err := users.Open(config.UsersFilePath())
+	if err != nil {
+		slog.Error("failed to load user registry", "error", err)
+		os.Exit(1)
+	}
+
+	for _, id := range cfg.AdminUserIDs {
+		if _, err := registry.Bootstrap(id, ""); err != nil {
+			slog.Error("failed to register admin", "user_id", id, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var channels []channel.Channel
+	for _, name := range cfg.EnabledChannels() {
+		ch, err := newChannel(name, cfg, agentInstance, toolEnv, registry)
+		if err != nil {
+			slog.Error("failed to create channel", "channel", name, "error", err)
+			os.Exit(1)
+		}
+		channels = append(channels, ch)
+	}
+
+	hub, err := channel.NewHub(channels...)
 	if err != nil {
-		slog.Error("failed to create telegram bot", "error", err)
+		slog.Error("failed to start channels", "error", err)
 		os.Exit(1)
 	}
+	toolEnv.Notifier = hub
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	go bot.Start(ctx)
-	slog.Info("bot is running — send a message on Telegram to interact")
+	done := make(chan error, 1)
+	go func() { done <- hub.Run(ctx) }()
+	slog.Info("agent is running", "channels", cfg.EnabledChannels())
 
-	<-ctx.Done()
-	slog.Info("shutdown signal received, stopping...")
-	time.Sleep(500 * time.Millisecond)
+	select {
+	case err := <-done:
+		if err != nil {
+			slog.Error("channels stopped", "error", err)
+			toolEnv.Close()
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, stopping...")
+		// Give the channels time to shut down, e.g. to unregister a webhook.
+		select {
+		case <-done:
+		case <-time.After(shutdownTimeout):
+		}
+	}
+	// Jobs run in their own process groups and would otherwise outlive Vayuu.
+	toolEnv.Close()
 	slog.Info("shutdown complete")
 }
+
+// newChannel creates the channel with the given name.
+func newChannel(name string, cfg *config.Config, agentInstance *agent.Agent, toolEnv *tools.ToolEnv, registry *users.Registry) (channel.Channel, error) {
+	switch name {
+	case "telegram":
+		return telegram.NewBot(cfg, agentInstance, toolEnv, registry)
+	default:
+		return nil, fmt.Errorf("unknown channel %q", name)
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -39,8 +43,14 @@ func Load() (*Config, error) {
 
 // validate checks that all required fields are present and valid
 func (c *Config) validate() error {
-	if c.TelegramToken == "" {
-		return fmt.Errorf("TELEGRAM_TOKEN is required")
+	if err := c.validateChannels(); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(c.Provider) {
+	case "", "openai", "ollama", "anthropic":
+	default:
+		return fmt.Errorf("PROVIDER must be one of openai, ollama or anthropic, got %q", c.Provider)
 	}
 
 	if c.ApiKey == "" {
@@ -59,8 +69,55 @@ func (c *Config) validate() error {
 		return fmt.Errorf("AGENT_WORKDIR is required")
 	}
 
-	if c.AllowedUsername == "" {
-		return fmt.Errorf("ALLOWED_USERNAME is required")
+	if c.AllowedUsername == "" && len(c.AdminUserIDs) == 0 {
+		return fmt.Errorf("ALLOWED_USERNAME or ADMIN_USER_IDS is required")
+	}
+
+	for i, fallback := range c.FallbackModels {
+		if fallback.Model == "" || fallback.ApiBaseURL == "" {
+			return fmt.Errorf("FALLBACK_MODELS[%d]: Model and ApiBaseURL are required", i)
+		}
+		switch strings.ToLower(fallback.Provider) {
+		case "", "openai", "ollama", "anthropic":
+		default:
+			return fmt.Errorf("FALLBACK_MODELS[%d]: unknown provider %q", i, fallback.Provider)
+		}
+	}
+
+	if c.ContextWindow < 0 || c.ContextTokenBudget < 0 || c.MaxToolResultChars < 0 {
+		return fmt.Errorf("context budget settings must not be negative")
+	}
+
+	if c.ContextWindow > 0 && c.ContextTokenBudget > c.ContextWindow {
+		return fmt.Errorf("CONTEXT_TOKEN_BUDGET must not exceed CONTEXT_WINDOW")
+	}
+
+	if c.MaxParallelTools < 0 {
+		return fmt.Errorf("MAX_PARALLEL_TOOLS must not be negative")
+	}
+
+	if c.MaxConcurrentChats < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_CHATS must not be negative")
+	}
+
+	if c.MaxImageDimension < 0 {
+		return fmt.Errorf("MAX_IMAGE_DIMENSION must not be negative")
+	}
+
+	if c.MaxDownloadMB < 0 {
+		return fmt.Errorf("MAX_DOWNLOAD_MB must not be negative")
+	}
+
+	if err := c.validateWebhook(); err != nil {
+		return err
+	}
+
+	if err := c.Policy.validate(); err != nil {
+		return fmt.Errorf("POLICY: %w", err)
+	}
+
+	if err := c.Sandbox.validate(); err != nil {
+		return err
 	}
 
 	return nil
@@ -177,6 +234,12 @@ func getConfigPath() string {
 	return filepath.Join(home, ".vayuu", configFileName)
 }
 
+// UsersFilePath returns the path to the user registry, which is ~/.vayuu/users.json.
+// It lives next to the config rather than in the workspace so the agent cannot edit it.
+func UsersFilePath() string {
+	return filepath.Join(filepath.Dir(getConfigPath()), usersFileName)
+}
+
 // isDevelopmentMode checks environment variables to determine if the application is running in development mode
 func isDevelopmentMode() bool {
 	values := []string{
@@ -198,7 +261,9 @@ func isDevelopmentMode() bool {
 // configFromEnv constructs a Config struct from environment variables using the provided getEnv function (e.g., os.Getenv)
 func configFromEnv(getEnv func(string) string) *Config {
 	return &Config{
+		Channels:        envList(getEnv, "CHANNELS"),
 		TelegramToken:   getEnv("TELEGRAM_TOKEN"),
+		Provider:        getEnv("PROVIDER"),
 		ApiKey:          getEnv("API_KEY"),
 		ApiBaseURL:      getEnv("API_BASE_URL"),
 		Model:           getEnv("MODEL"),
@@ -206,9 +271,238 @@ func configFromEnv(getEnv func(string) string) *Config {
 		AllowedUsername: getEnv("ALLOWED_USERNAME"),
 		OllamaBaseURL:   getEnv("OLLAMA_BASE_URL"),
 		OllamaModel:     getEnv("OLLAMA_MODEL"),
+		AdminUserIDs:    envInt64List(getEnv, "ADMIN_USER_IDS"),
+
+		ContextWindow:      envInt(getEnv, "CONTEXT_WINDOW"),
+		ContextTokenBudget: envInt(getEnv, "CONTEXT_TOKEN_BUDGET"),
+		MaxToolResultChars: envInt(getEnv, "MAX_TOOL_RESULT_CHARS"),
+
+		MaxParallelTools:   envInt(getEnv, "MAX_PARALLEL_TOOLS"),
+		MaxConcurrentChats: envInt(getEnv, "MAX_CONCURRENT_CHATS"),
+		MaxDownloadMB:      envInt(getEnv, "MAX_DOWNLOAD_MB"),
+
+		STTBaseURL:       getEnv("STT_BASE_URL"),
+		STTApiKey:        getEnv("STT_API_KEY"),
+		STTModel:         getEnv("STT_MODEL"),
+		STTLanguage:      getEnv("STT_LANGUAGE"),
+		WhisperBinary:    getEnv("WHISPER_BINARY"),
+		WhisperModelPath: getEnv("WHISPER_MODEL_PATH"),
+
+		TelegramAPIURL:     getEnv("TELEGRAM_API_URL"),
+		WebhookURL:         getEnv("WEBHOOK_URL"),
+		WebhookListenAddr:  getEnv("WEBHOOK_LISTEN_ADDR"),
+		WebhookSecretToken: getEnv("WEBHOOK_SECRET_TOKEN"),
+		WebhookCertFile:    getEnv("WEBHOOK_CERT_FILE"),
+		WebhookKeyFile:     getEnv("WEBHOOK_KEY_FILE"),
+
+		Vision:            envBool(getEnv, "VISION"),
+		MaxImageDimension: envInt(getEnv, "MAX_IMAGE_DIMENSION"),
+
+		FallbackModels: envModelEndpoints(getEnv, "FALLBACK_MODELS"),
+		Policy:         envPolicy(getEnv, "POLICY"),
+
+		Sandbox: SandboxConfig{
+			Backend:       getEnv("SANDBOX"),
+			NoNetwork:     envBool(getEnv, "SANDBOX_NO_NETWORK"),
+			Hide:          envList(getEnv, "SANDBOX_HIDE"),
+			CPUSeconds:    envInt(getEnv, "SANDBOX_CPU_SECONDS"),
+			MemoryMB:      envInt(getEnv, "SANDBOX_MEMORY_MB"),
+			MaxProcesses:  envInt(getEnv, "SANDBOX_MAX_PROCESSES"),
+			MaxFileSizeMB: envInt(getEnv, "SANDBOX_MAX_FILE_SIZE_MB"),
+		},
 	}
 }
 
+// validateChannels checks that each enabled channel is known, listed once and has its credentials set
+func (c *Config) validateChannels() error {
+	seen := make(map[string]bool)
+	for _, name := range c.EnabledChannels() {
+		switch name {
+		case "telegram":
+			if c.TelegramToken == "" {
+				return fmt.Errorf("TELEGRAM_TOKEN is required")
+			}
+		default:
+			return fmt.Errorf("CHANNELS: unknown channel %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("CHANNELS: %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// validateWebhook checks the webhook settings, which only matter when WebhookURL is set
+func (c *Config) validateWebhook() error {
+	if c.TelegramAPIURL != "" {
+		if u, err := url.Parse(c.TelegramAPIURL); err != nil || u.Host == "" {
+			return fmt.Errorf("TELEGRAM_API_URL must be an absolute URL")
+		}
+	}
+
+	if c.WebhookURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(c.WebhookURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("WEBHOOK_URL must be an absolute https URL")
+	}
+
+	if (c.WebhookCertFile == "") != (c.WebhookKeyFile == "") {
+		return fmt.Errorf("WEBHOOK_CERT_FILE and WEBHOOK_KEY_FILE must be set together")
+	}
+
+	// Telegram accepts 1-256 characters: letters, digits, _ and -.
+	if token := c.WebhookSecretToken; token != "" {
+		if len(token) > 256 || strings.IndexFunc(token, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+		}) >= 0 {
+			return fmt.Errorf("WEBHOOK_SECRET_TOKEN must be up to 256 letters, digits, _ or -")
+		}
+	}
+
+	return nil
+}
+
+// validate checks the decisions and patterns of a policy
+func (p PolicyConfig) validate() error {
+	for name, decision := range map[string]string{"HomePaths": p.HomePaths, "OutsideWrites": p.OutsideWrites, "Default": p.Default} {
+		if !validDecision(decision) {
+			return fmt.Errorf("%s: unknown decision %q", name, decision)
+		}
+	}
+
+	for i, rule := range p.Rules {
+		if !validDecision(rule.Decision) || rule.Decision == "" {
+			return fmt.Errorf("Rules[%d]: decision must be auto, confirm or deny, got %q", i, rule.Decision)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("Rules[%d]: invalid pattern: %w", i, err)
+		}
+	}
+
+	if p.ApprovalTimeoutSeconds < 0 {
+		return fmt.Errorf("ApprovalTimeoutSeconds must not be negative")
+	}
+	return nil
+}
+
+// validate checks the backend and limits of a sandbox
+func (s SandboxConfig) validate() error {
+	switch strings.ToLower(s.Backend) {
+	case "", "none", "bwrap", "native":
+	default:
+		return fmt.Errorf("SANDBOX must be none, bwrap or native, got %q", s.Backend)
+	}
+
+	if s.CPUSeconds < 0 || s.MemoryMB < 0 || s.MaxProcesses < 0 || s.MaxFileSizeMB < 0 {
+		return fmt.Errorf("sandbox limits must not be negative")
+	}
+	return nil
+}
+
+// validDecision reports whether a policy decision is known; empty means the default
+func validDecision(decision string) bool {
+	switch strings.ToLower(decision) {
+	case "", "auto", "confirm", "deny":
+		return true
+	default:
+		return false
+	}
+}
+
+// envPolicy reads a JSON policy from an environment variable, returning the zero policy if it is unset or invalid
+func envPolicy(getEnv func(string) string, key string) PolicyConfig {
+	var policy PolicyConfig
+	value := strings.TrimSpace(getEnv(key))
+	if value == "" {
+		return policy
+	}
+
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		slog.Warn("ignoring invalid policy environment variable", "key", key, "error", err)
+		return PolicyConfig{}
+	}
+	return policy
+}
+
+// envModelEndpoints reads a JSON array of model endpoints from an environment variable, returning nil if it is unset or invalid
+func envModelEndpoints(getEnv func(string) string, key string) []ModelEndpoint {
+	value := strings.TrimSpace(getEnv(key))
+	if value == "" {
+		return nil
+	}
+
+	var endpoints []ModelEndpoint
+	if err := json.Unmarshal([]byte(value), &endpoints); err != nil {
+		slog.Warn("ignoring invalid model list environment variable", "key", key, "error", err)
+		return nil
+	}
+	return endpoints
+}
+
+// envInt reads an integer environment variable, returning 0 if it is unset or invalid
+func envInt(getEnv func(string) string, key string) int {
+	value := strings.TrimSpace(getEnv(key))
+	if value == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("ignoring invalid integer environment variable", "key", key, "value", value)
+		return 0
+	}
+	return n
+}
+
+// envBool reads a boolean environment variable, returning false if it is unset or invalid
+func envBool(getEnv func(string) string, key string) bool {
+	value := strings.TrimSpace(getEnv(key))
+	if value == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("ignoring invalid boolean environment variable", "key", key, "value", value)
+		return false
+	}
+	return b
+}
+
+// envInt64List reads a comma-separated list of integers from an environment variable, skipping invalid entries
+func envInt64List(getEnv func(string) string, key string) []int64 {
+	var values []int64
+	for _, field := range strings.Split(getEnv(key), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			slog.Warn("ignoring invalid integer in environment variable", "key", key, "value", field)
+			continue
+		}
+		values = append(values, n)
+	}
+	return values
+}
+
+// envList parses a comma-separated environment variable, skipping empty fields.
+func envList(getEnv func(string) string, key string) []string {
+	var values []string
+	for _, field := range strings.Split(getEnv(key), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			values = append(values, field)
+		}
+	}
+	return values
+}
+
 // normalizeConfigPaths expands and validates paths in the config. If createWorkDir is true, it creates the work directory if it doesn't exist.
 func normalizeConfigPaths(cfg *Config, createWorkDir bool) error {
 	if cfg == nil {
@@ -227,6 +521,21 @@ func normalizeConfigPaths(cfg *Config, createWorkDir bool) error {
 		}
 	}
 
+	paths := []*string{&cfg.WebhookCertFile, &cfg.WebhookKeyFile}
+	for i := range cfg.Sandbox.Hide {
+		paths = append(paths, &cfg.Sandbox.Hide[i])
+	}
+	for _, path := range paths {
+		if *path == "" {
+			continue
+		}
+		normalized, err := normalizePath(*path)
+		if err != nil {
+			return err
+		}
+		*path = normalized
+	}
+
 	return nil
 }
 
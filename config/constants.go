@@ -6,6 +6,7 @@ import (
 
 const (
 	configFileName = "vayuuConfig.json"
+	usersFileName  = "users.json"
 	vayuuASCII     = `██╗   ██╗ █████╗ ██╗   ██╗██╗   ██╗██╗   ██╗
 ██║   ██║██╔══██╗╚██╗ ██╔╝██║   ██║██║   ██║
 ██║   ██║███████║ ╚████╔╝ ██║   ██║██║   ██║
@@ -27,11 +28,17 @@ var (
 		},
 		{
 			Label:    "Allowed Telegram Username (without @)",
-			Help:     "Only this username can talk to the bot.",
+			Help:     "This user becomes the admin and can invite others.",
 			Required: true,
 		},
 		{
-			Label:    "API Key (OpenAI compatible)",
+			Label:    "LLM Provider (openai, ollama or anthropic)",
+			Help:     "Wire format of your LLM endpoint. openai works with any OpenAI-compatible API, including Ollama's /v1.",
+			Default:  "openai",
+			Required: true,
+		},
+		{
+			Label:    "API Key",
 			Help:     "Your LLM provider API key. For Ollama, press enter for ollama",
 			Default:  "ollama",
 			Required: true,
@@ -30,6 +30,7 @@ func RunSetup() error {
 
 	config := &Config{
 		TelegramToken:   result.TelegramToken,
+		Provider:        result.Provider,
 		ApiKey:          result.ApiKey,
 		ApiBaseURL:      result.ApiBaseURL,
 		Model:           result.Model,
@@ -87,10 +88,11 @@ func runSetupForm() (setupResult, error) {
 	return setupResult{
 		TelegramToken:   resultModel.values[0],
 		AllowedUsername: resultModel.values[1],
-		ApiKey:          resultModel.values[2],
-		ApiBaseURL:      resultModel.values[3],
-		Model:           resultModel.values[4],
-		AgentWorkDir:    resultModel.values[5],
+		Provider:        resultModel.values[2],
+		ApiKey:          resultModel.values[3],
+		ApiBaseURL:      resultModel.values[4],
+		Model:           resultModel.values[5],
+		AgentWorkDir:    resultModel.values[6],
 	}, nil
 }
 
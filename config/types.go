@@ -7,14 +7,134 @@ import (
 
 // Config holds all application configuration
 type Config struct {
+	// Channels are the front ends the agent is reachable through, run side by side. Empty enables telegram.
+	Channels []string
+
 	TelegramToken   string
+	Provider        string // LLM wire format: openai (default), ollama or anthropic
 	ApiKey          string
 	ApiBaseURL      string
 	Model           string
 	AgentWorkDir    string
-	AllowedUsername string
+	AllowedUsername string // Claims the admin role on first contact when no admin is registered yet
 	OllamaBaseURL   string
 	OllamaModel     string
+
+	// AdminUserIDs are Telegram user IDs registered as admins at startup.
+	AdminUserIDs []int64
+
+	// Context budgeting. Zero values fall back to per-model defaults.
+	ContextWindow      int // Model context window in tokens
+	ContextTokenBudget int // Prompt size that triggers history compaction
+	MaxToolResultChars int // Tool results above this size are spilled to the workspace
+
+	// MaxParallelTools bounds how many tool calls from one model turn run at once. Zero uses the default.
+	MaxParallelTools int
+
+	// MaxConcurrentChats bounds how many chats are processed at once. Zero uses the default.
+	MaxConcurrentChats int
+
+	// Vision marks the primary model as able to see images. Images sent by users are then passed to it.
+	Vision bool
+
+	// MaxImageDimension bounds the longer side of images sent to the model, in pixels. Zero uses the default.
+	MaxImageDimension int
+
+	// Speech-to-text for voice notes: either an OpenAI-compatible endpoint (e.g. a whisper.cpp server at
+	// http://localhost:8080/v1) or a local whisper.cpp binary, which takes precedence. Both are off when unset.
+	STTBaseURL       string
+	STTApiKey        string
+	STTModel         string // Model sent to the endpoint; defaults to whisper-1
+	STTLanguage      string // Spoken language code, e.g. "en"; empty lets the model detect it
+	WhisperBinary    string // Path or name of the whisper.cpp CLI, e.g. whisper-cli
+	WhisperModelPath string // ggml model file passed to the binary with -m
+
+	// TelegramAPIURL points the bot at another Bot API server, e.g. a local one or a fake for tests.
+	TelegramAPIURL string
+
+	// Webhook mode: Telegram posts updates to WebhookURL instead of the bot polling for them. The path of the URL
+	// is served on WebhookListenAddr, over TLS when a certificate is set or as plain HTTP behind a reverse proxy.
+	WebhookURL         string // Public URL registered with Telegram; empty uses long polling
+	WebhookListenAddr  string // Defaults to :8443
+	WebhookSecretToken string // Expected in the X-Telegram-Bot-Api-Secret-Token header; random per start if empty
+	WebhookCertFile    string // TLS certificate; empty serves plain HTTP
+	WebhookKeyFile     string // TLS private key
+
+	// MaxDownloadMB limits the size of files users send to the bot. Zero uses the Bot API limit of 20 MB.
+	MaxDownloadMB int
+
+	// FallbackModels are tried in order when the primary model keeps failing.
+	FallbackModels []ModelEndpoint
+
+	// Policy decides which tool calls run right away, wait for the user's approval or are refused.
+	Policy PolicyConfig
+
+	// Sandbox confines the shell commands the agent runs.
+	Sandbox SandboxConfig
+}
+
+// SandboxConfig isolates shell commands in Linux namespaces. Inside, the system is read-only, the home directory
+// and the Vayuu config are hidden and only the run's workspace and a private /tmp are writable.
+type SandboxConfig struct {
+	Backend   string   // none (default), bwrap to use bubblewrap, or native to set up the namespaces directly
+	NoNetwork bool     // Commands get a network namespace with only loopback
+	Hide      []string // Further paths hidden from commands, e.g. /srv/secrets
+
+	// Resource limits applied to every command, with any backend. Zero leaves a limit unset.
+	CPUSeconds    int
+	MemoryMB      int // Virtual memory of each process
+	MaxProcesses  int // Counted across all processes of the user running Vayuu
+	MaxFileSizeMB int // Largest file a command may write
+}
+
+// PolicyConfig configures the approval of tool calls. Decisions are auto, confirm or deny.
+type PolicyConfig struct {
+	// Rules are checked in order and the first match decides. Without rules, built-in rules ask before
+	// destructive commands such as rm -rf, sudo or dd.
+	Rules []PolicyRule
+
+	WriteAllowlist []string // Directories besides the workspace that may be written without confirmation
+	HomePaths      string   // Decision for calls touching a ~/ path outside the workspace; defaults to confirm
+	OutsideWrites  string   // Decision for writes outside the workspace and the allowlist; defaults to confirm
+	Default        string   // Decision when no rule matches; defaults to auto
+
+	ApprovalTimeoutSeconds int // How long a call waits for approval before it is refused; defaults to 5 minutes
+}
+
+// PolicyRule classifies tool calls by tool name and command.
+type PolicyRule struct {
+	Tool     string // Empty matches every tool
+	Pattern  string // Regular expression matched against each shell command of the call; empty matches every call
+	Decision string
+	Reason   string // Shown to the user and the model
+}
+
+// ModelEndpoint identifies a model served by a provider
+type ModelEndpoint struct {
+	Provider   string
+	ApiKey     string
+	ApiBaseURL string
+	Model      string
+	Vision     bool // The model accepts images
+}
+
+// EnabledChannels returns the names of the channels to run, defaulting to telegram.
+func (c *Config) EnabledChannels() []string {
+	if len(c.Channels) == 0 {
+		return []string{"telegram"}
+	}
+	return c.Channels
+}
+
+// PrimaryModel returns the endpoint of the main configured model
+func (c *Config) PrimaryModel() ModelEndpoint {
+	return ModelEndpoint{
+		Provider:   c.Provider,
+		ApiKey:     c.ApiKey,
+		ApiBaseURL: c.ApiBaseURL,
+		Model:      c.Model,
+		Vision:     c.Vision,
+	}
 }
 
 type promptRequest struct {
@@ -28,6 +148,7 @@ type promptRequest struct {
 type setupResult struct {
 	TelegramToken   string
 	AllowedUsername string
+	Provider        string
 	ApiKey          string
 	ApiBaseURL      string
 	Model           string
@@ -2,17 +2,20 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Shreehari-Acharya/vayuu/config"
 	"github.com/Shreehari-Acharya/vayuu/internal/memory"
+	"github.com/Shreehari-Acharya/vayuu/internal/policy"
 	"github.com/openai/openai-go/v3"
 )
 // CreateAgent initializes a new Agent instance with the provided system prompt and configuration.
-// It sets up the OpenAI client, memory manager, and other necessary fields.
+// It sets up the LLM provider, memory manager, and other necessary fields.
 func CreateAgent(systemPrompt string, cfg *config.Config) (*Agent, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("config is nil")
@@ -21,17 +24,43 @@ func CreateAgent(systemPrompt string, cfg *config.Config) (*Agent, error) {
 		return nil, fmt.Errorf("model is required")
 	}
 
+	backends, err := newBackends(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := policy.New(cfg.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+
 	agent := &Agent{
-		client:       createLLMInstance(cfg),
-		model:        cfg.Model,
+		cfg:          cfg,
+		backends:     backends,
 		tools:        make(map[string]Tool),
 		toolsDirty:   true,
 		systemPrompt: systemPrompt,
 		workDir:      cfg.AgentWorkDir,
 		memoryWriter: memory.NewFileMemoryWriter(cfg.AgentWorkDir),
+		sessions:     memory.NewSessionStore(cfg.AgentWorkDir),
+		budget:       newTokenBudget(cfg, cfg.Model),
+		maxParallel:  cfg.MaxParallelTools,
+		maxImageDim:  cfg.MaxImageDimension,
+
+		policy:          engine,
+		approvalTimeout: time.Duration(cfg.Policy.ApprovalTimeoutSeconds) * time.Second,
+	}
+	if agent.maxParallel == 0 {
+		agent.maxParallel = defaultMaxParallelTools
+	}
+	if agent.maxImageDim == 0 {
+		agent.maxImageDim = defaultMaxImageDimension
+	}
+	if agent.approvalTimeout == 0 {
+		agent.approvalTimeout = defaultApprovalTimeout
 	}
 
-	mgr, err := memory.NewMemoryManagerWithDB(cfg.AgentWorkDir, cfg)
+	mgr, err := memory.NewMemoryManagerWithDB(cfg.AgentWorkDir, cfg, agent.chatText)
 	if err != nil {
 		slog.Warn("failed to initialize memory manager with DB, trying vector only", "error", err)
 		mgr, err = memory.NewMemoryManager(memory.DefaultConfig())
@@ -44,6 +73,7 @@ func CreateAgent(systemPrompt string, cfg *config.Config) (*Agent, error) {
 
 	agent.memoryMgr = mgr
 
+	slog.Info("agent created", "provider", backends[0].provider.Name(), "model", cfg.Model, "fallbacks", len(backends)-1)
 	return agent, nil
 }
 
@@ -63,6 +93,9 @@ func (a *Agent) RegisterTool(tool Tool) error {
 		return fmt.Errorf("tool %q: already registered", tool.Name)
 	}
 
+	a.toolsMu.Lock()
+	defer a.toolsMu.Unlock()
+
 	a.tools[tool.Name] = tool
 	a.toolsDirty = true
 	a.toolsCache = nil
@@ -70,9 +103,16 @@ func (a *Agent) RegisterTool(tool Tool) error {
 }
 
 // RunAgent processes user input through the agent's reasoning loop, invoking tools as needed.
+// The conversation continues the session of the chat the input came from, which is updated once the run succeeds.
+// Images in the input are shown to the primary model if it is vision-capable; history only keeps their paths.
+// When onEvent is set, responses are streamed and progress is reported through it as the run goes.
 // It returns the final response generated by the agent or an error if processing fails.
-func (a *Agent) RunAgent(ctx context.Context, userInput string) (string, error) {
-	slog.Info("agent invoked", "input_len", len(userInput))
+func (a *Agent) RunAgent(ctx context.Context, origin Origin, input Input, onEvent EventFunc) (*Reply, error) {
+	userInput := input.Text
+	slog.Info("agent invoked", "conversation", origin.Key(), "input_len", len(userInput), "images", len(input.Images))
+
+	ctx = WithOrigin(ctx, origin)
+	ctx = memory.WithNamespace(ctx, ScopeFrom(ctx).Namespace)
 
 	systemPrompt := a.systemPrompt
 
@@ -85,146 +125,274 @@ func (a *Agent) RunAgent(ctx context.Context, userInput string) (string, error)
 		}
 	}
 
-	messages := []openai.ChatCompletionMessageParamUnion{
-		systemMsg(systemPrompt),
-		userMsg(userInput),
+	session, err := a.sessions.Load(origin.Key())
+	if err != nil {
+		slog.Warn("failed to load session, starting fresh", "conversation", origin.Key(), "error", err)
+	}
+
+	conv := &conversation{
+		messages: append([]openai.ChatCompletionMessageParamUnion{systemMsg(systemPrompt)}, session...),
+		backend:  responder{backend: a.chain()[0]},
+		onEvent:  onEvent,
 	}
+	conv.add(a.inputMsg(ctx, input))
 
-	response, history, err := a.runLoop(ctx, messages)
+	response, err := a.runLoop(ctx, conv)
+	stopped := false
 	if err != nil {
-		return "", err
+		if ctx.Err() == nil {
+			return nil, err
+		}
+		// The run was cancelled: keep what was done so far and tell the user about it.
+		stopped = true
+		response = conv.stopSummary()
+		conv.add(assistantMsg(openai.ChatCompletionMessage{Content: response}))
+		slog.Info("agent stopped", "conversation", origin.Key(), "completed_tools", len(conv.steps))
+	}
+
+	if err := a.sessions.Save(origin.Key(), withoutImages(conv.messages[1:])); err != nil {
+		slog.Warn("failed to persist session", "conversation", origin.Key(), "error", err)
 	}
 
 	if a.memoryWriter != nil {
-		if err := a.memoryWriter.Write(history); err != nil {
+		if err := a.memoryWriter.Write(ctx, withoutImages(conv.turn)); err != nil {
 			slog.Warn("failed to persist memory", "error", err)
 		}
 	}
 
-	if a.memoryMgr != nil && response != "" {
+	if a.memoryMgr != nil && response != "" && !stopped {
+		// The run's context ends with the run, so extraction continues without its cancellation.
+		bgCtx := context.WithoutCancel(ctx)
 		go func() {
-			if err := a.memoryMgr.ProcessConversation(ctx, userInput, response); err != nil {
+			if err := a.memoryMgr.ProcessConversation(bgCtx, userInput, response); err != nil {
 				slog.Warn("failed to process conversation for memory", "error", err)
 			}
 		}()
 	}
 
-	slog.Info("agent completed", "response_len", len(response))
-	return response, nil
+	answered := conv.backend
+	slog.Info("agent completed", "conversation", origin.Key(), "model", answered.model, "response_len", len(response))
+
+	reply := &Reply{Text: response, Fallback: answered.fallback, Attachments: conv.attachments, Stopped: stopped}
+	if len(a.chain()) > 1 {
+		reply.Model = answered.model
+	}
+	return reply, nil
+}
+
+// inputMsg builds the user message of a run, attaching the input's images when the primary model can see them.
+func (a *Agent) inputMsg(ctx context.Context, input Input) openai.ChatCompletionMessageParamUnion {
+	if len(input.Images) == 0 || !a.chain()[0].vision {
+		return userMsg(input.Text)
+	}
+
+	images, notes := a.loadImages(ctx, input.Images)
+	text := strings.Join(append([]string{input.Text}, notes...), "\n")
+	if len(images) == 0 {
+		return userMsg(text)
+	}
+	return userImageMsg(text, images)
+}
+
+// ResetSession clears the conversation history of a chat so the next message starts fresh.
+func (a *Agent) ResetSession(origin Origin) error {
+	if err := a.sessions.Clear(origin.Key()); err != nil {
+		return fmt.Errorf("reset session %s: %w", origin.Key(), err)
+	}
+	slog.Info("session reset", "conversation", origin.Key())
+	return nil
 }
 
 // runLoop is the core reasoning loop of the agent. It sends messages to the LLM, processes responses,
 // and handles tool calls until a final response is generated or an error occurs.
-func (a *Agent) runLoop(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) (string, []openai.ChatCompletionMessageParamUnion, error) {
-	var consecutiveErrors, iterations int
+// History is compacted whenever the prompt grows past the token budget.
+func (a *Agent) runLoop(ctx context.Context, conv *conversation) (string, error) {
+	var contextErrors, iterations int
 
 	for {
 		if err := ctx.Err(); err != nil {
-			return "", messages, fmt.Errorf("context cancelled: %w", err)
+			return "", fmt.Errorf("context cancelled: %w", err)
 		}
 		if iterations >= maxAgentIterations {
-			return "", messages, fmt.Errorf("exceeded max iterations (%d)", maxAgentIterations)
-		}
-		if consecutiveErrors >= maxConsecutiveLLMErrors {
-			return "", messages, fmt.Errorf("exceeded max consecutive LLM errors (%d)", maxConsecutiveLLMErrors)
+			return "", fmt.Errorf("exceeded max iterations (%d)", maxAgentIterations)
 		}
 		iterations++
 
-		resp, err := a.requestCompletion(ctx, messages)
+		a.compactIfNeeded(ctx, conv, false)
+
+		resp, answered, err := a.requestCompletion(ctx, conv)
 		if err != nil {
-			consecutiveErrors++
-			slog.Error("LLM request failed", "attempt", consecutiveErrors, "max", maxConsecutiveLLMErrors, "error", err)
-			continue
+			var llmErr *LLMError
+			if errors.As(err, &llmErr) && llmErr.Kind == ErrorContextLength && contextErrors < maxConsecutiveLLMErrors {
+				contextErrors++
+				slog.Warn("prompt rejected as too long, compacting history", "attempt", contextErrors, "error", err)
+				a.compactIfNeeded(ctx, conv, true)
+				continue
+			}
+			return "", fmt.Errorf("LLM request failed: %w", err)
 		}
-		consecutiveErrors = 0
+		contextErrors = 0
+		conv.backend = answered
 
-		llmMsg := resp.Choices[0].Message
+		llmMsg := resp.Message
 
 		if len(llmMsg.ToolCalls) == 0 {
 			finalText := memory.CleanThinkingTags(llmMsg.Content)
-			messages = append(messages, assistantMsg(llmMsg))
-			return finalText, messages, nil
+			conv.add(assistantMsg(llmMsg))
+			return finalText, nil
 		}
 
-		messages = append(messages, assistantMsg(llmMsg))
-		if err := a.dispatchToolCalls(llmMsg.ToolCalls, &messages); err != nil {
-			return "", messages, err
+		conv.add(assistantMsg(llmMsg))
+		a.dispatchToolCalls(ctx, llmMsg.ToolCalls, conv)
+	}
+}
+
+// add appends messages to both the model history and the record of the current turn.
+func (c *conversation) add(msgs ...openai.ChatCompletionMessageParamUnion) {
+	c.messages = append(c.messages, msgs...)
+	c.turn = append(c.turn, msgs...)
+}
+
+// stopSummary describes the tool calls completed before the run was stopped.
+func (c *conversation) stopSummary() string {
+	if len(c.steps) == 0 {
+		return "Stopped. Nothing was completed before the stop."
+	}
+
+	var b strings.Builder
+	b.WriteString("Stopped. Completed before the stop:")
+	for _, step := range c.steps {
+		b.WriteString("\n- " + step.name)
+		switch {
+		case step.interrupted:
+			b.WriteString(" (interrupted)")
+		case step.failed:
+			b.WriteString(" (failed)")
 		}
 	}
+	return b.String()
+}
+
+// emit reports a progress event to the listener, if any.
+func (c *conversation) emit(event Event) {
+	if c.onEvent != nil {
+		c.onEvent(event)
+	}
 }
 
 // dispatchToolCalls processes each tool call from the LLM response, invoking the corresponding tool handlers
 // and appending the results back to the message history for further reasoning.
-func (a *Agent) dispatchToolCalls(calls []openai.ChatCompletionMessageToolCallUnion, messages *[]openai.ChatCompletionMessageParamUnion) error {
+// Independent calls run in parallel; results are always added in the order the model requested them.
+// Oversized results are spilled to the workspace before they reach the history.
+func (a *Agent) dispatchToolCalls(ctx context.Context, calls []openai.ChatCompletionMessageToolCallUnion, conv *conversation) {
 	slog.Info("dispatching tool calls", "count", len(calls))
 
-	for i, call := range calls {
-		result, err := a.invokeTool(call)
-		if err != nil {
-			return err
+	var imagePaths []string
+	current := conv.backend
+	runs := a.runToolCalls(ctx, calls, conv)
+	for i, run := range runs {
+		call := run.call
+		if len(run.result.Images) > 0 {
+			if current.vision {
+				imagePaths = append(imagePaths, run.result.Images...)
+			} else {
+				run.result = ErrorResult("the current model (%s) cannot see images", current.model)
+			}
 		}
+		result := a.limitToolResult(ctx, call, run.result.modelContent())
 
 		preview := result
 		if len(preview) > resultPreviewLength {
 			preview = preview[:resultPreviewLength] + resultPreviewSuffix
 		}
-		slog.Debug("tool result", "index", i+1, "name", call.Function.Name, "preview", preview)
+		slog.Debug("tool result", "index", i+1, "name", call.Function.Name, "error", run.result.IsError, "preview", preview)
+
+		conv.attachments = append(conv.attachments, run.result.Attachments...)
+		conv.steps = append(conv.steps, toolStep{
+			name:        call.Function.Name,
+			failed:      run.result.IsError,
+			interrupted: run.result.IsError && ctx.Err() != nil,
+		})
+		conv.add(toolCallMsg(call.ID, result))
+	}
 
-		*messages = append(*messages, toolCallMsg(call.ID, result))
+	// Tool messages can only carry text, so images requested by tools follow in a user message.
+	if len(imagePaths) > 0 {
+		images, notes := a.loadImages(ctx, imagePaths)
+		text := strings.Join(append([]string{"Images returned by tools:"}, notes...), "\n")
+		if len(images) == 0 {
+			conv.add(userMsg(text))
+			return
+		}
+		conv.add(userImageMsg(text, images))
 	}
-	return nil
 }
 
-// invokeTool safely calls the registered tool handler for a given tool call, recovering from panics and returning errors as needed.
-func (a *Agent) invokeTool(call openai.ChatCompletionMessageToolCallUnion) (result string, err error) {
+// invokeTool validates the arguments of a tool call and runs its handler.
+// Every failure, including unknown tools, invalid arguments and panics, becomes an error result for the model to act on.
+func (a *Agent) invokeTool(ctx context.Context, call openai.ChatCompletionMessageToolCallUnion) (result ToolResult) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("tool %q panicked: %v", call.Function.Name, r)
+			slog.Error("tool panicked", "name", call.Function.Name, "panic", r)
+			result = ErrorResult("tool %q panicked: %v", call.Function.Name, r)
 		}
 	}()
 
+	scope := ScopeFrom(ctx)
 	tool, ok := a.tools[call.Function.Name]
+	if ok && !scope.allows(call.Function.Name) {
+		slog.Warn("tool call denied by scope", "name", call.Function.Name)
+		return ErrorResult("tool %q is not permitted for this user", call.Function.Name)
+	}
 	if !ok {
 		available := make([]string, 0, len(a.tools))
 		for name := range a.tools {
-			available = append(available, name)
+			if scope.allows(name) {
+				available = append(available, name)
+			}
 		}
-		return "", fmt.Errorf("unknown tool %q (available: %v)", call.Function.Name, available)
+		sort.Strings(available)
+		return ErrorResult("unknown tool %q (available: %s)", call.Function.Name, strings.Join(available, ", "))
 	}
 
-	args := map[string]any{}
-	if call.Function.Arguments != "" {
-		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
-			return "", fmt.Errorf("tool %q: invalid arguments: %w", call.Function.Name, err)
-		}
+	raw, repaired, err := parseArguments(tool.Parameters, call.Function.Arguments)
+	if err != nil {
+		slog.Warn("rejected tool arguments", "name", call.Function.Name, "error", err)
+		return ErrorResult("%v. Fix the arguments to match the tool schema and call it again", err)
+	}
+	if repaired {
+		slog.Info("repaired tool arguments", "name", call.Function.Name, "original", call.Function.Arguments, "repaired", string(raw))
 	}
 
 	start := time.Now()
-	result = tool.Handler(args)
-	slog.Info("tool executed", "name", call.Function.Name, "duration", time.Since(start))
+	result = tool.Handler(ctx, raw)
+	slog.Info("tool executed", "name", call.Function.Name, "duration", time.Since(start), "error", result.IsError, "metadata", result.Metadata)
 
-	return result, nil
+	return result
 }
 
-// requestCompletion sends the current message history to the OpenAI API and returns the response, handling errors and validating the result.
-func (a *Agent) requestCompletion(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) (*openai.ChatCompletion, error) {
-	resp, err := a.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model:       a.model,
-		Messages:    messages,
-		Tools:       a.openAITools(),
-		Temperature: openai.Float(defaultTemperature),
-	})
-	if err != nil {
-		return nil, err
-	}
-	if resp == nil || len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("LLM returned no choices")
+// requestCompletion sends the current message history down the model fallback chain and returns the response
+// along with the backend that answered. The response is streamed when the conversation has a listener.
+func (a *Agent) requestCompletion(ctx context.Context, conv *conversation) (*CompletionResponse, responder, error) {
+	var onText func(string)
+	if conv.onEvent != nil {
+		onText = func(text string) {
+			conv.emit(Event{Kind: EventText, Text: memory.CleanThinkingTags(text)})
+		}
 	}
-	return resp, nil
+
+	return a.complete(ctx, CompletionRequest{
+		Messages:    conv.messages,
+		Tools:       a.scopedTools(ScopeFrom(ctx)),
+		Temperature: defaultTemperature,
+	}, onText)
 }
 
 // openAITools returns the current list of registered tools in the format expected by the OpenAI API, using caching for efficiency.
 func (a *Agent) openAITools() []openai.ChatCompletionToolUnionParam {
+	a.toolsMu.Lock()
+	defer a.toolsMu.Unlock()
+
 	if !a.toolsDirty && a.toolsCache != nil {
 		return a.toolsCache
 	}
@@ -232,3 +400,19 @@ func (a *Agent) openAITools() []openai.ChatCompletionToolUnionParam {
 	a.toolsDirty = false
 	return a.toolsCache
 }
+
+// scopedTools returns the tool definitions the scope permits, so the model never sees tools it can't call.
+func (a *Agent) scopedTools(scope Scope) []openai.ChatCompletionToolUnionParam {
+	tools := a.openAITools()
+	if scope.AllowTool == nil {
+		return tools
+	}
+
+	allowed := make([]openai.ChatCompletionToolUnionParam, 0, len(tools))
+	for _, t := range tools {
+		if t.OfFunction != nil && scope.allows(t.OfFunction.Function.Name) {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed
+}
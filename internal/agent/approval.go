@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/policy"
+)
+
+// authorizeCall asks the policy whether a tool call may run, waiting for the user's approval when it has to.
+// It returns false with the result to give the model when the call is refused, denied or not answered in time.
+// Calls the scope forbids are left to invokeTool, which rejects them without bothering the user.
+func (a *Agent) authorizeCall(ctx context.Context, run *toolRun, conv *conversation) (ToolResult, bool) {
+	name := run.call.Function.Name
+	scope := ScopeFrom(ctx)
+	if a.policy == nil || !scope.allows(name) {
+		return ToolResult{}, true
+	}
+
+	verdict := a.policy.Evaluate(policy.Call{
+		Tool:     name,
+		Commands: run.access.Commands,
+		Reads:    run.access.Reads,
+		Writes:   run.access.Writes,
+		WorkDir:  a.runWorkDir(ctx),
+	})
+
+	switch verdict.Decision {
+	case policy.Auto:
+		return ToolResult{}, true
+	case policy.Deny:
+		slog.Warn("tool call denied by policy", "name", name, "reason", verdict.Reason)
+		return ErrorResult("this call is blocked by policy (%s). Do not retry it; find another way or ask the user", verdict.Reason), false
+	}
+
+	approve := approverFrom(ctx)
+	if approve == nil {
+		slog.Warn("tool call needs approval but nobody can approve it", "name", name, "reason", verdict.Reason)
+		return ErrorResult("this call needs the user's approval (%s), which can't be asked for here", verdict.Reason), false
+	}
+
+	conv.emit(Event{Kind: EventApproval, Tool: name})
+	slog.Info("waiting for approval", "name", name, "reason", verdict.Reason)
+
+	approveCtx, cancel := context.WithTimeout(ctx, a.approvalTimeout)
+	defer cancel()
+	approved, err := approve(approveCtx, ApprovalRequest{
+		Tool:      name,
+		Summary:   callSummary(run),
+		Reason:    verdict.Reason,
+		AdminOnly: verdict.Home && scope.Confined,
+	})
+
+	switch {
+	case ctx.Err() != nil:
+		return ErrorResult("run cancelled while waiting for approval"), false
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.Info("approval timed out", "name", name)
+		return ErrorResult("the user did not approve this call within %v, so it was not run", a.approvalTimeout), false
+	case err != nil:
+		slog.Warn("failed to ask for approval", "name", name, "error", err)
+		return ErrorResult("couldn't ask the user for approval: %v", err), false
+	case !approved:
+		slog.Info("tool call denied by user", "name", name)
+		return ErrorResult("the user denied this call, so it was not run. Don't retry it without asking"), false
+	}
+
+	slog.Info("tool call approved by user", "name", name)
+	return ToolResult{}, true
+}
+
+// callSummary shows what a call does: its shell commands, or else its arguments.
+func callSummary(run *toolRun) string {
+	if len(run.access.Commands) > 0 {
+		return strings.Join(run.access.Commands, "\n")
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(run.call.Function.Arguments), "", "  "); err != nil {
+		return run.call.Function.Arguments
+	}
+	return buf.String()
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Shreehari-Acharya/vayuu/config"
+	"github.com/openai/openai-go/v3"
+)
+
+// newTokenBudget derives the token budget for a model.
+// Explicit config values take precedence over the per-model defaults.
+func newTokenBudget(cfg *config.Config, model string) tokenBudget {
+	profile := lookupModelProfile(model)
+
+	budget := tokenBudget{
+		window:         profile.contextWindow,
+		charsPerToken:  profile.charsPerToken,
+		maxResultChars: defaultMaxToolResultChars,
+	}
+	if cfg.ContextWindow > 0 {
+		budget.window = cfg.ContextWindow
+	}
+	budget.limit = int(float64(budget.window) * defaultBudgetRatio)
+	if cfg.ContextTokenBudget > 0 {
+		budget.limit = cfg.ContextTokenBudget
+	}
+	if cfg.MaxToolResultChars > 0 {
+		budget.maxResultChars = cfg.MaxToolResultChars
+	}
+
+	return budget
+}
+
+// tokenBudget returns the token budget of the current primary model.
+func (a *Agent) tokenBudget() tokenBudget {
+	a.backendsMu.RLock()
+	defer a.backendsMu.RUnlock()
+	return a.budget
+}
+
+// lookupModelProfile returns the context window and tokenizer ratio for a model name.
+// Unknown models get a conservative default.
+func lookupModelProfile(model string) modelProfile {
+	name := strings.ToLower(model)
+	for _, profile := range modelProfiles {
+		if strings.Contains(name, profile.match) {
+			return profile
+		}
+	}
+	return modelProfile{contextWindow: defaultContextWindow, charsPerToken: defaultCharsPerToken}
+}
+
+// estimateTokens approximates the prompt size of a request from its message and tool definitions.
+func (b tokenBudget) estimateTokens(messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolUnionParam) int {
+	chars := 0
+	for _, msg := range messages {
+		chars += messageChars(msg)
+	}
+
+	tokens := int(float64(chars)/b.charsPerToken) + len(messages)*perMessageTokenOverhead
+	if len(tools) > 0 {
+		if data, err := json.Marshal(tools); err == nil {
+			tokens += int(float64(len(data)) / b.charsPerToken)
+		}
+	}
+
+	return tokens
+}
+
+// messageChars returns the number of characters a message contributes to the prompt.
+func messageChars(msg openai.ChatCompletionMessageParamUnion) int {
+	switch {
+	case msg.OfSystem != nil:
+		return len(msg.OfSystem.Content.OfString.Value)
+	case msg.OfUser != nil:
+		text, images := userContent(msg.OfUser)
+		return len(text) + len(images)*int(imageTokenEstimate*defaultCharsPerToken)
+	case msg.OfAssistant != nil:
+		n := len(msg.OfAssistant.Content.OfString.Value)
+		for _, call := range msg.OfAssistant.ToolCalls {
+			if call.OfFunction != nil {
+				n += len(call.OfFunction.Function.Name) + len(call.OfFunction.Function.Arguments)
+			}
+		}
+		return n
+	case msg.OfTool != nil:
+		return len(msg.OfTool.Content.OfString.Value)
+	default:
+		return 0
+	}
+}
+
+// limitToolResult keeps an oversized tool result within the budget.
+// The full output is spilled to a workspace file and the model receives the head and tail with a pointer to it.
+func (a *Agent) limitToolResult(ctx context.Context, call openai.ChatCompletionMessageToolCallUnion, result string) string {
+	max := a.tokenBudget().maxResultChars
+	if max <= 0 || len(result) <= max {
+		return result
+	}
+
+	// Cut on character boundaries so neither part ends in half of a multi-byte character.
+	headEnd := max * 3 / 4
+	for headEnd > 0 && !utf8.RuneStart(result[headEnd]) {
+		headEnd--
+	}
+	tailStart := len(result) - (max - max*3/4)
+	for tailStart < len(result) && !utf8.RuneStart(result[tailStart]) {
+		tailStart++
+	}
+	head, tail := result[:headEnd], result[tailStart:]
+	truncated := tailStart - headEnd
+
+	relPath, err := a.spillToolResult(ctx, call, result)
+	if err != nil {
+		slog.Warn("failed to spill tool result, truncating", "name", call.Function.Name, "error", err)
+		return fmt.Sprintf("%s\n\n[... %d characters truncated ...]\n\n%s", head, truncated, tail)
+	}
+
+	slog.Info("tool result spilled to workspace", "name", call.Function.Name, "size", len(result), "path", relPath)
+	return fmt.Sprintf("%s\n\n[... %d characters truncated. Full output (%d characters) saved to %s — inspect it with read_file or grep instead of re-running the tool ...]\n\n%s",
+		head, truncated, len(result), relPath, tail)
+}
+
+// spillToolResult writes a full tool result under the run's workspace and returns its path relative to it.
+func (a *Agent) spillToolResult(ctx context.Context, call openai.ChatCompletionMessageToolCallUnion, result string) (string, error) {
+	workDir := a.runWorkDir(ctx)
+	if workDir == "" {
+		return "", fmt.Errorf("work directory not configured")
+	}
+
+	dir := filepath.Join(workDir, toolOutputDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.txt", time.Now().Format("20060102-150405"), sanitizeFileName(call.Function.Name), sanitizeFileName(call.ID))
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(result), 0644); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(toolOutputDirName, name), nil
+}
+
+// runWorkDir returns the workspace of the current run: the scope's, or the agent workspace without one.
+func (a *Agent) runWorkDir(ctx context.Context) string {
+	if dir := ScopeFrom(ctx).WorkDir; dir != "" {
+		return dir
+	}
+	return a.workDir
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names.
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/memory"
+	"github.com/openai/openai-go/v3"
+)
+
+// compactIfNeeded replaces older turns with an LLM-generated summary when the prompt exceeds the token budget,
+// or unconditionally when force is set (e.g. after the provider rejected the prompt as too long).
+// The system prompt and the most recent messages are always kept verbatim.
+func (a *Agent) compactIfNeeded(ctx context.Context, conv *conversation, force bool) {
+	tools := a.openAITools()
+	budget := a.tokenBudget()
+	before := budget.estimateTokens(conv.messages, tools)
+	if !force && (budget.limit <= 0 || before <= budget.limit) {
+		return
+	}
+
+	start, end := compactionRange(conv.messages)
+	if end <= start {
+		slog.Warn("context over budget but nothing left to compact", "tokens", before, "budget", budget.limit)
+		return
+	}
+
+	compacted := conv.messages[start:end]
+	summary, err := a.summarize(ctx, compacted)
+	if err != nil {
+		slog.Warn("failed to summarize history, dropping oldest messages", "error", err)
+		summary = "Earlier messages were dropped to fit the context window."
+	}
+
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(conv.messages)-len(compacted)+2)
+	messages = append(messages, conv.messages[0], systemMsg(summaryPrefix+summary))
+	messages = append(messages, conv.messages[end:]...)
+	conv.messages = messages
+
+	after := budget.estimateTokens(conv.messages, tools)
+	slog.Info("compacted conversation history",
+		"messages", len(compacted),
+		"tokens_before", before,
+		"tokens_after", after,
+		"budget", budget.limit,
+		"summary_len", len(summary),
+	)
+}
+
+// compactionRange returns the half-open range of messages to fold into a summary.
+// It starts after the system prompt and includes any previous summary. The kept messages start at a user turn,
+// as some providers require the conversation to open with one, which also keeps tool results with their calls.
+// When no user turn follows the usual cut, the cut moves back to the last one before it.
+func compactionRange(messages []openai.ChatCompletionMessageParamUnion) (int, int) {
+	start := 1
+	cut := len(messages) - compactKeepMessages
+	end := cut
+	for end > start && end < len(messages) && messages[end].OfUser == nil {
+		end++
+	}
+	if end >= len(messages) {
+		end = cut
+		for end > start && messages[end].OfUser == nil {
+			end--
+		}
+	}
+	if end <= start {
+		return start, start
+	}
+	return start, end
+}
+
+// summarize asks the model to condense a slice of history into a short summary.
+func (a *Agent) summarize(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) (string, error) {
+	resp, _, err := a.complete(ctx, CompletionRequest{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			systemMsg(summarizePrompt),
+			userMsg(renderTranscript(messages)),
+		},
+		Temperature: defaultTemperature,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	summary := memory.CleanThinkingTags(resp.Message.Content)
+	if summary == "" {
+		return "", fmt.Errorf("LLM returned an empty summary")
+	}
+	return summary, nil
+}
+
+// renderTranscript flattens messages into plain text for summarization, clipping long tool output.
+func renderTranscript(messages []openai.ChatCompletionMessageParamUnion) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		switch {
+		case msg.OfSystem != nil:
+			fmt.Fprintf(&sb, "[earlier summary]\n%s\n\n", strings.TrimPrefix(msg.OfSystem.Content.OfString.Value, summaryPrefix))
+		case msg.OfUser != nil:
+			text, _ := userContent(msg.OfUser)
+			fmt.Fprintf(&sb, "User: %s\n\n", text)
+		case msg.OfAssistant != nil:
+			if content := memory.CleanThinkingTags(msg.OfAssistant.Content.OfString.Value); content != "" {
+				fmt.Fprintf(&sb, "Assistant: %s\n\n", content)
+			}
+			for _, call := range msg.OfAssistant.ToolCalls {
+				if call.OfFunction != nil {
+					fmt.Fprintf(&sb, "Assistant called %s(%s)\n\n", call.OfFunction.Function.Name, clip(call.OfFunction.Function.Arguments, transcriptClipLength))
+				}
+			}
+		case msg.OfTool != nil:
+			fmt.Fprintf(&sb, "Tool result: %s\n\n", clip(msg.OfTool.Content.OfString.Value, transcriptClipLength))
+		}
+	}
+	return sb.String()
+}
+
+// clip shortens text to at most n bytes, marking the cut. It cuts on a character boundary.
+func clip(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n] + resultPreviewSuffix
+}
@@ -1,10 +1,104 @@
 package agent
 
+import "time"
+
 // Agent configuration constants.
 const (
 	maxConsecutiveLLMErrors = 3
+	retryBaseDelay          = 1 * time.Second
+	retryMaxDelay           = 30 * time.Second
+	maxRetryAfter           = 60 * time.Second
 	maxAgentIterations      = 20
+	defaultMaxParallelTools = 4
 	defaultTemperature      = 0.2
 	resultPreviewLength     = 50
 	resultPreviewSuffix     = "..."
+	defaultApprovalTimeout  = 5 * time.Minute
+)
+
+// Image constants.
+const (
+	defaultMaxImageDimension = 1024
+	maxImagePixels           = 50_000_000 // Refuse to decode anything larger, e.g. decompression bombs
+	imageJPEGQuality         = 85
+	imageLabelPrefix         = "[image: "
+	imageTokenEstimate       = 1000 // Rough prompt cost of one image
+)
+
+// Kinds of progress events emitted while the agent runs.
+const (
+	EventText      EventKind = "text"       // Text of the response being streamed so far
+	EventToolStart EventKind = "tool_start" // A tool call is about to run
+	EventToolEnd   EventKind = "tool_end"   // A tool call has finished
+	EventApproval  EventKind = "approval"   // A tool call is waiting for the user's approval
+)
+
+// Provider names and wire-format constants.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderOllama    = "ollama"
+	ProviderAnthropic = "anthropic"
+
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicAPIVersion     = "2023-06-01"
+	defaultMaxTokens        = 4096
+	maxErrorBodySize        = 4096
+	maxStreamLineSize       = 1024 * 1024
+	extractionTemperature   = 0.3
+)
+
+// contextLengthMarkers are fragments of provider error messages that indicate an oversized prompt.
+var contextLengthMarkers = []string{
+	"context length",
+	"context_length",
+	"context window",
+	"maximum context",
+	"too many tokens",
+	"prompt is too long",
+	"input is too long",
+}
+
+// Context budgeting constants.
+const (
+	defaultContextWindow      = 32768
+	defaultCharsPerToken      = 4.0
+	defaultBudgetRatio        = 0.75
+	defaultMaxToolResultChars = 24000
+	perMessageTokenOverhead   = 4
+	compactKeepMessages       = 8
+	transcriptClipLength      = 2000
+	toolOutputDirName         = "tool_outputs"
+	summaryPrefix             = "Summary of the earlier conversation:\n"
+	summarizePrompt           = `You condense conversation history for an AI assistant that is running out of context.
+Write a concise summary of the conversation below, preserving:
+- what the user asked for and any decisions or preferences they stated
+- facts, file paths, commands and results the assistant discovered
+- what has been completed and what is still pending
+Respond with the summary only.`
 )
+
+// modelProfiles maps model name fragments to their context window and average characters per token.
+// More specific fragments must come before more general ones.
+var modelProfiles = []modelProfile{
+	{match: "gpt-4.1", contextWindow: 1047576, charsPerToken: 4.0},
+	{match: "gpt-4o", contextWindow: 128000, charsPerToken: 4.0},
+	{match: "gpt-4-turbo", contextWindow: 128000, charsPerToken: 4.0},
+	{match: "gpt-4", contextWindow: 8192, charsPerToken: 4.0},
+	{match: "gpt-3.5", contextWindow: 16385, charsPerToken: 4.0},
+	{match: "o1", contextWindow: 200000, charsPerToken: 4.0},
+	{match: "o3", contextWindow: 200000, charsPerToken: 4.0},
+	{match: "o4", contextWindow: 200000, charsPerToken: 4.0},
+	{match: "claude", contextWindow: 200000, charsPerToken: 3.5},
+	{match: "gemini", contextWindow: 1048576, charsPerToken: 4.0},
+	{match: "kimi", contextWindow: 262144, charsPerToken: 3.5},
+	{match: "deepseek", contextWindow: 65536, charsPerToken: 3.5},
+	{match: "qwen", contextWindow: 32768, charsPerToken: 3.5},
+	{match: "llama3.1", contextWindow: 131072, charsPerToken: 4.0},
+	{match: "llama3.2", contextWindow: 131072, charsPerToken: 4.0},
+	{match: "llama3.3", contextWindow: 131072, charsPerToken: 4.0},
+	{match: "llama3", contextWindow: 8192, charsPerToken: 4.0},
+	{match: "mistral", contextWindow: 32768, charsPerToken: 3.5},
+	{match: "gemma3", contextWindow: 131072, charsPerToken: 4.0},
+	{match: "gemma", contextWindow: 8192, charsPerToken: 4.0},
+	{match: "phi", contextWindow: 16384, charsPerToken: 3.5},
+}
@@ -0,0 +1,56 @@
+package agent
+
+import "context"
+
+// originKey is the context key under which the origin of the current run is stored.
+type originKey struct{}
+
+// WithOrigin returns a context carrying the channel and chat a run belongs to.
+func WithOrigin(ctx context.Context, origin Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// OriginFrom returns the channel and chat of the current run, as seen by tool handlers.
+func OriginFrom(ctx context.Context) (Origin, bool) {
+	origin, ok := ctx.Value(originKey{}).(Origin)
+	return origin, ok
+}
+
+// Key identifies the conversation of an origin across channels, e.g. "telegram:123". Users of a chat share it.
+func (o Origin) Key() string {
+	return o.Channel + ":" + o.Chat
+}
+
+// scopeKey is the context key under which the scope of the current run is stored.
+type scopeKey struct{}
+
+// WithScope returns a context whose run is restricted to the given scope.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope of the current run. Without one, every tool is allowed in the agent workspace.
+func ScopeFrom(ctx context.Context) Scope {
+	scope, _ := ctx.Value(scopeKey{}).(Scope)
+	return scope
+}
+
+// allows reports whether the scope permits calling a tool.
+func (s Scope) allows(tool string) bool {
+	return s.AllowTool == nil || s.AllowTool(tool)
+}
+
+// approverKey is the context key under which the approver of the current run is stored.
+type approverKey struct{}
+
+// WithApprover returns a context whose tool calls can be approved by the user through approve.
+// Without an approver, calls that need approval are refused.
+func WithApprover(ctx context.Context, approve ApproveFunc) context.Context {
+	return context.WithValue(ctx, approverKey{}, approve)
+}
+
+// approverFrom returns the approver of the current run, if any.
+func approverFrom(ctx context.Context) ApproveFunc {
+	approve, _ := ctx.Value(approverKey{}).(ApproveFunc)
+	return approve
+}
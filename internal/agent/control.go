@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/memory"
+)
+
+// chain returns the current fallback chain. SetModel replaces the slice rather than modifying it,
+// so callers can keep using the returned chain while the model is switched.
+func (a *Agent) chain() []backend {
+	a.backendsMu.RLock()
+	defer a.backendsMu.RUnlock()
+	return a.backends
+}
+
+// Models returns the primary model followed by the fallback models.
+func (a *Agent) Models() []string {
+	backends := a.chain()
+	models := make([]string, len(backends))
+	for i, b := range backends {
+		models[i] = b.model
+	}
+	return models
+}
+
+// SetModel switches the primary model for subsequent requests. A model of the fallback chain keeps its own
+// endpoint and vision setting and trades places with the old primary, so it isn't tried twice and the chain
+// keeps its length; any other name is sent to the primary endpoint. The change lasts until restart.
+func (a *Agent) SetModel(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("model name is required")
+	}
+
+	a.backendsMu.Lock()
+	defer a.backendsMu.Unlock()
+
+	backends := slices.Clone(a.backends)
+	for i, b := range backends[1:] {
+		if b.model == name {
+			backends[0], backends[i+1] = b, backends[0]
+			break
+		}
+	}
+	backends[0].model = name
+
+	a.backends = backends
+	a.budget = newTokenBudget(a.cfg, name)
+
+	slog.Info("model switched", "model", name, "provider", backends[0].provider.Name())
+	return nil
+}
+
+// Tools returns the registered tools the scope permits, sorted by name.
+func (a *Agent) Tools(scope Scope) []Tool {
+	a.toolsMu.Lock()
+	defer a.toolsMu.Unlock()
+
+	tools := make([]Tool, 0, len(a.tools))
+	for name, tool := range a.tools {
+		if scope.allows(name) {
+			tools = append(tools, tool)
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// Memory returns the long-term memory manager, or nil if memory is unavailable.
+func (a *Agent) Memory() *memory.MemoryManager {
+	return a.memoryMgr
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ErrorKind classifies LLM request failures so the agent can decide whether to retry, fall back or give up.
+type ErrorKind string
+
+// LLM error kinds.
+const (
+	ErrorRateLimit     ErrorKind = "rate_limit"
+	ErrorAuth          ErrorKind = "auth"
+	ErrorContextLength ErrorKind = "context_length"
+	ErrorServer        ErrorKind = "server"
+	ErrorNetwork       ErrorKind = "network"
+	ErrorBadRequest    ErrorKind = "bad_request"
+	ErrorUnknown       ErrorKind = "unknown"
+)
+
+// LLMError is a classified error returned by a provider.
+type LLMError struct {
+	Kind       ErrorKind
+	StatusCode int           // HTTP status, 0 if the request never got a response
+	RetryAfter time.Duration // Server-requested delay before retrying, 0 if none
+	Provider   string
+	Model      string
+	Err        error
+}
+
+// Error implements the error interface.
+func (e *LLMError) Error() string {
+	return fmt.Sprintf("%s/%s: %s: %v", e.Provider, e.Model, e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying provider error.
+func (e *LLMError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether retrying the same model may succeed.
+func (e *LLMError) Retryable() bool {
+	switch e.Kind {
+	case ErrorRateLimit, ErrorServer, ErrorNetwork, ErrorUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// statusError is returned by postJSON for non-2xx responses.
+type statusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.URL, e.StatusCode, e.Body)
+}
+
+// classifyError wraps a provider error into an LLMError.
+func classifyError(err error, provider, model string) *LLMError {
+	llmErr := &LLMError{Kind: ErrorUnknown, Provider: provider, Model: model, Err: err}
+
+	var apiErr *openai.Error
+	var httpErr *statusError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr):
+		llmErr.StatusCode = apiErr.StatusCode
+		if apiErr.Response != nil {
+			llmErr.RetryAfter = parseRetryAfter(apiErr.Response.Header.Get("Retry-After"))
+		}
+		llmErr.Kind = kindForStatus(apiErr.StatusCode, apiErr.Message+" "+apiErr.Code)
+	case errors.As(err, &httpErr):
+		llmErr.StatusCode = httpErr.StatusCode
+		llmErr.RetryAfter = httpErr.RetryAfter
+		llmErr.Kind = kindForStatus(httpErr.StatusCode, httpErr.Body)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		llmErr.Kind = ErrorNetwork
+	}
+
+	return llmErr
+}
+
+// kindForStatus maps an HTTP status and error message to an ErrorKind.
+func kindForStatus(status int, message string) ErrorKind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrorRateLimit
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return ErrorAuth
+	case status == http.StatusRequestEntityTooLarge, isContextLengthMessage(message):
+		return ErrorContextLength
+	case status >= 500:
+		return ErrorServer
+	case status >= 400:
+		return ErrorBadRequest
+	default:
+		return ErrorUnknown
+	}
+}
+
+// isContextLengthMessage detects the various ways providers report an oversized prompt.
+func isContextLengthMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
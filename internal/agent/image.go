@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// encodedImage is an image ready to be sent to a vision-capable model.
+type encodedImage struct {
+	Label    string // Shown to the model next to the image, and kept in history once the image is dropped
+	MimeType string
+	Data     string // Base64-encoded image bytes
+}
+
+// dataURL returns the image as a data URL, the form OpenAI-compatible APIs accept inline.
+func (img encodedImage) dataURL() string {
+	return "data:" + img.MimeType + ";base64," + img.Data
+}
+
+// loadImages encodes images for the model, labelling each with its path relative to the run's workspace.
+// Images that can't be loaded are reported in the returned notes instead.
+func (a *Agent) loadImages(ctx context.Context, paths []string) ([]encodedImage, []string) {
+	var images []encodedImage
+	var notes []string
+	for _, path := range paths {
+		label := a.displayPath(ctx, path)
+		img, err := loadImage(path, a.maxImageDim)
+		if err != nil {
+			slog.Warn("failed to load image", "path", path, "error", err)
+			notes = append(notes, fmt.Sprintf("[image %s could not be loaded: %v]", label, err))
+			continue
+		}
+		img.Label = label
+		images = append(images, img)
+	}
+	return images, notes
+}
+
+// loadImage reads an image and re-encodes it so that neither side exceeds maxDim pixels.
+// JPEG and PNG files that already fit are sent unchanged.
+func loadImage(path string, maxDim int) (encodedImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return encodedImage{}, err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return encodedImage{}, fmt.Errorf("unsupported image format: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return encodedImage{}, fmt.Errorf("image too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+
+	fits := maxDim <= 0 || max(cfg.Width, cfg.Height) <= maxDim
+	if fits && (format == "jpeg" || format == "png") {
+		return encodedImage{MimeType: "image/" + format, Data: base64.StdEncoding.EncodeToString(data)}, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return encodedImage{}, fmt.Errorf("decode image: %w", err)
+	}
+	if !fits {
+		src = downscale(src, maxDim)
+	}
+
+	var buf bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&buf, src)
+	} else {
+		err = jpeg.Encode(&buf, src, &jpeg.Options{Quality: imageJPEGQuality})
+	}
+	if err != nil {
+		return encodedImage{}, fmt.Errorf("encode image: %w", err)
+	}
+
+	mimeType := "image/jpeg"
+	if format == "png" {
+		mimeType = "image/png"
+	}
+	return encodedImage{MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(buf.Bytes())}, nil
+}
+
+// downscale shrinks an image so its longer side is maxDim pixels, averaging the source pixels behind each output pixel.
+func downscale(src image.Image, maxDim int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	scale := float64(maxDim) / float64(max(w, h))
+	dw, dh := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, b, a = r+int(p[0]), g+int(p[1]), b+int(p[2]), a+int(p[3])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// displayPath returns path relative to the run's workspace when it lies inside it.
+func (a *Agent) displayPath(ctx context.Context, path string) string {
+	if rel, err := filepath.Rel(a.runWorkDir(ctx), path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// userImageMsg creates a user message with text followed by labelled images.
+func userImageMsg(text string, images []encodedImage) openai.ChatCompletionMessageParamUnion {
+	parts := []openai.ChatCompletionContentPartUnionParam{openai.TextContentPart(text)}
+	for _, img := range images {
+		parts = append(parts,
+			openai.TextContentPart(imageLabelPrefix+img.Label+"]"),
+			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: img.dataURL()}),
+		)
+	}
+	return openai.ChatCompletionMessageParamUnion{
+		OfUser: &openai.ChatCompletionUserMessageParam{
+			Role:    "user",
+			Content: openai.ChatCompletionUserMessageParamContentUnion{OfArrayOfContentParts: parts},
+		},
+	}
+}
+
+// userContent returns the text and images of a user message.
+func userContent(msg *openai.ChatCompletionUserMessageParam) (string, []encodedImage) {
+	if len(msg.Content.OfArrayOfContentParts) == 0 {
+		return msg.Content.OfString.Value, nil
+	}
+
+	var texts []string
+	var images []encodedImage
+	for _, part := range msg.Content.OfArrayOfContentParts {
+		switch {
+		case part.OfText != nil:
+			texts = append(texts, part.OfText.Text)
+		case part.OfImageURL != nil:
+			if img, ok := parseDataURL(part.OfImageURL.ImageURL.URL); ok {
+				images = append(images, img)
+			}
+		}
+	}
+	return strings.Join(texts, "\n"), images
+}
+
+// parseDataURL splits a base64 data URL into its MIME type and data.
+func parseDataURL(url string) (encodedImage, bool) {
+	meta, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	mimeType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !ok || !isBase64 || !strings.HasPrefix(url, "data:") {
+		return encodedImage{}, false
+	}
+	return encodedImage{MimeType: mimeType, Data: data}, true
+}
+
+// withoutImages replaces messages carrying images with their text, in which the image labels remain.
+// Images are only sent for the turn they arrive in; history, sessions and memory keep the text.
+func withoutImages(messages []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	out := messages
+	copied := false
+	for i, msg := range messages {
+		if msg.OfUser == nil || len(msg.OfUser.Content.OfArrayOfContentParts) == 0 {
+			continue
+		}
+		if !copied {
+			out = append([]openai.ChatCompletionMessageParamUnion(nil), messages...)
+			copied = true
+		}
+		text, _ := userContent(msg.OfUser)
+		out[i] = userMsg(text)
+	}
+	return out
+}
@@ -1,19 +1,23 @@
 package agent
 
 import (
-	"github.com/Shreehari-Acharya/vayuu/config"
+	"context"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/memory"
 	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/option"
 )
 
-// createLLMInstance creates a new OpenAI client using the provided configuration.
-func createLLMInstance(cfg *config.Config) *openai.Client {
-	client := openai.NewClient(
-		option.WithAPIKey(cfg.ApiKey),
-		option.WithBaseURL(cfg.ApiBaseURL),
-	)
-
-	return &client
+// chatText sends a plain prompt without tools through the agent's provider and returns the cleaned reply.
+// It is handed to the memory subsystem so fact extraction shares the agent's provider.
+func (a *Agent) chatText(ctx context.Context, system, prompt string) (string, error) {
+	resp, _, err := a.complete(ctx, CompletionRequest{
+		Messages:    []openai.ChatCompletionMessageParamUnion{systemMsg(system), userMsg(prompt)},
+		Temperature: extractionTemperature,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return memory.CleanThinkingTags(resp.Message.Content), nil
 }
 
 // systemMsg creates a system role message.
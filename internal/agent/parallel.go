@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// runToolCalls executes a batch of tool calls on a bounded worker pool.
+// Each call waits for the earlier calls it conflicts with, so reads of a file never race a write to it
+// and exclusive tools run alone. The returned runs are in the original call order.
+func (a *Agent) runToolCalls(ctx context.Context, calls []openai.ChatCompletionMessageToolCallUnion, conv *conversation) []*toolRun {
+	runs := make([]*toolRun, len(calls))
+	for i, call := range calls {
+		run := &toolRun{call: call, access: a.toolAccess(ctx, call), done: make(chan struct{})}
+		for _, prev := range runs[:i] {
+			if conflicts(prev.access, run.access) {
+				run.deps = append(run.deps, prev)
+			}
+		}
+		runs[i] = run
+	}
+
+	if len(runs) == 1 || a.maxParallel <= 1 {
+		for _, run := range runs {
+			a.executeRun(ctx, run, conv)
+		}
+		return runs
+	}
+
+	slots := make(chan struct{}, a.maxParallel)
+	var wg sync.WaitGroup
+	for _, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, dep := range run.deps {
+				<-dep.done
+			}
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			a.executeRun(ctx, run, conv)
+		}()
+	}
+	wg.Wait()
+
+	return runs
+}
+
+// executeRun invokes a single tool call once the policy allows it, reporting its start and end, and marks it done.
+func (a *Agent) executeRun(ctx context.Context, run *toolRun, conv *conversation) {
+	defer close(run.done)
+
+	conv.emit(Event{Kind: EventToolStart, Tool: run.call.Function.Name})
+	// The calls this one waited for are done, and what it touches can depend on them, e.g. the line a shell session
+	// has been typed so far, so the policy checks the access as it is now rather than when the batch was planned.
+	run.access = a.toolAccess(ctx, run.call)
+	if result, ok := a.authorizeCall(ctx, run, conv); ok {
+		run.result = a.invokeTool(ctx, run.call)
+	} else {
+		run.result = result
+	}
+	conv.emit(Event{Kind: EventToolEnd, Tool: run.call.Function.Name})
+}
+
+// toolAccess asks the tool what the call touches, using the same repaired arguments the handler will see.
+// Unknown tools, tools without an AccessFunc and calls with invalid arguments are treated as exclusive.
+func (a *Agent) toolAccess(ctx context.Context, call openai.ChatCompletionMessageToolCallUnion) (access ToolAccess) {
+	tool, ok := a.tools[call.Function.Name]
+	if !ok || tool.Access == nil {
+		return ToolAccess{Exclusive: true}
+	}
+
+	raw, _, err := parseArguments(tool.Parameters, call.Function.Arguments)
+	if err != nil {
+		return ToolAccess{Exclusive: true}
+	}
+	args := map[string]any{}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return ToolAccess{Exclusive: true}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Warn("tool access check panicked, running exclusively", "name", call.Function.Name, "panic", r)
+			access = ToolAccess{Exclusive: true}
+		}
+	}()
+	return tool.Access(ctx, args)
+}
+
+// conflicts reports whether two calls must not run at the same time.
+func conflicts(a, b ToolAccess) bool {
+	if a.Exclusive || b.Exclusive {
+		return true
+	}
+	return anyOverlap(a.Writes, b.Writes) || anyOverlap(a.Writes, b.Reads) || anyOverlap(a.Reads, b.Writes)
+}
+
+// anyOverlap reports whether any path in a overlaps any path in b.
+func anyOverlap(a, b []string) bool {
+	for _, p := range a {
+		for _, q := range b {
+			if pathsOverlap(p, q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathsOverlap reports whether two paths are the same or one contains the other.
+func pathsOverlap(p, q string) bool {
+	p, q = filepath.Clean(p), filepath.Clean(q)
+	if p == q {
+		return true
+	}
+	return strings.HasPrefix(p, q+string(filepath.Separator)) || strings.HasPrefix(q, p+string(filepath.Separator))
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/config"
+	"github.com/openai/openai-go/v3"
+)
+
+// Provider is an LLM backend that serves chat completions with tool calls.
+// Messages and tools use the OpenAI chat format; implementations translate them to their own wire format.
+type Provider interface {
+	// Name identifies the provider in logs.
+	Name() string
+	// Complete sends a chat completion request and returns the assistant message.
+	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+}
+
+// CompletionRequest is a provider-agnostic chat completion request.
+// When OnDelta is set the provider streams the response and calls it with each new piece of text.
+type CompletionRequest struct {
+	Model       string
+	Messages    []openai.ChatCompletionMessageParamUnion
+	Tools       []openai.ChatCompletionToolUnionParam
+	Temperature float64
+	OnDelta     func(text string)
+}
+
+// CompletionResponse holds the assistant message produced by a provider.
+type CompletionResponse struct {
+	Message openai.ChatCompletionMessage
+	Model   string // Model that actually answered, as reported by the provider
+}
+
+// NewProvider creates the provider for a model endpoint.
+// An empty provider name selects the OpenAI-compatible provider.
+func NewProvider(endpoint config.ModelEndpoint) (Provider, error) {
+	switch strings.ToLower(endpoint.Provider) {
+	case "", ProviderOpenAI:
+		return newOpenAIProvider(endpoint.ApiKey, endpoint.ApiBaseURL), nil
+	case ProviderOllama:
+		return newOllamaProvider(endpoint.ApiBaseURL), nil
+	case ProviderAnthropic:
+		return newAnthropicProvider(endpoint.ApiKey, endpoint.ApiBaseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (supported: %s, %s, %s)", endpoint.Provider, ProviderOpenAI, ProviderOllama, ProviderAnthropic)
+	}
+}
+
+// postJSON sends a JSON request and decodes a JSON response, returning an error for non-2xx statuses.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out any) error {
+	resp, err := doPost(ctx, client, url, headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// doPost sends a JSON request and returns the response for the caller to read, e.g. as a stream.
+// Non-2xx statuses are returned as a statusError and the body is closed.
+func doPost(ctx context.Context, client *http.Client, url string, headers map[string]string, body any) (*http.Response, error) {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, &statusError{
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(data)),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	return resp, nil
+}
+
+// toolCallNames maps tool call IDs to tool names, for wire formats that identify tool results by name.
+func toolCallNames(messages []openai.ChatCompletionMessageParamUnion) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		if msg.OfAssistant == nil {
+			continue
+		}
+		for _, call := range msg.OfAssistant.ToolCalls {
+			if call.OfFunction != nil {
+				names[call.OfFunction.ID] = call.OfFunction.Function.Name
+			}
+		}
+	}
+	return names
+}
+
+// rawArguments parses tool call arguments into a JSON object, falling back to an empty object.
+func rawArguments(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// trimAPIVersion strips a trailing /v1 from a base URL for providers whose paths include the version.
+func trimAPIVersion(baseURL string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return strings.TrimSuffix(baseURL, "/v1")
+}
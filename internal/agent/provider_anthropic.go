@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// anthropicProvider talks to the Anthropic Messages API.
+type anthropicProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// anthropicRequest is the request body of /v1/messages.
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+// anthropicStreamEvent is the data payload of a server-sent event from a streaming /v1/messages request.
+type anthropicStreamEvent struct {
+	Type         string            `json:"type"`
+	Index        int               `json:"index"`
+	Message      anthropicResponse `json:"message"`
+	ContentBlock anthropicBlock    `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicResponse is the response body of /v1/messages.
+type anthropicResponse struct {
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+}
+
+// anthropicMessage is a single message made of content blocks.
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is a text, tool_use or tool_result content block.
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Source    *anthropicImage `json:"source,omitempty"`
+}
+
+// anthropicImage is the source of an image block.
+type anthropicImage struct {
+	Type      string `json:"type"` // Always "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// anthropicTool is a tool definition in Anthropic's format.
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+// newAnthropicProvider creates an Anthropic provider. An empty base URL selects the public API.
+func newAnthropicProvider(apiKey, baseURL string) *anthropicProvider {
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
+	}
+	return &anthropicProvider{
+		apiKey:  apiKey,
+		baseURL: trimAPIVersion(baseURL),
+		client:  &http.Client{},
+	}
+}
+
+// Name identifies the provider in logs.
+func (p *anthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// Complete sends the request to /v1/messages and converts the reply to the OpenAI message format.
+func (p *anthropicProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	system, messages := toAnthropicMessages(req.Messages)
+	body := anthropicRequest{
+		Model:       req.Model,
+		System:      system,
+		Messages:    messages,
+		Tools:       toAnthropicTools(req.Tools),
+		MaxTokens:   defaultMaxTokens,
+		Temperature: req.Temperature,
+		Stream:      req.OnDelta != nil,
+	}
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicAPIVersion,
+	}
+
+	var resp anthropicResponse
+	var err error
+	if body.Stream {
+		resp, err = p.stream(ctx, body, headers, req.OnDelta)
+	} else {
+		err = postJSON(ctx, p.client, p.baseURL+"/v1/messages", headers, body, &resp)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("anthropic messages: %w", err)
+	}
+
+	var text []string
+	msg := openai.ChatCompletionMessage{}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			msg.ToolCalls = append(msg.ToolCalls, openai.ChatCompletionMessageToolCallUnion{
+				ID:   block.ID,
+				Type: "function",
+				Function: openai.ChatCompletionMessageFunctionToolCallFunction{
+					Name:      block.Name,
+					Arguments: string(rawArguments(string(block.Input))),
+				},
+			})
+		}
+	}
+	msg.Content = strings.Join(text, "\n")
+
+	return &CompletionResponse{Message: msg, Model: resp.Model}, nil
+}
+
+// stream reads the server-sent events of a streaming /v1/messages request, forwarding text deltas
+// and assembling content blocks (including tool_use input) into a complete response.
+func (p *anthropicProvider) stream(ctx context.Context, body anthropicRequest, headers map[string]string, onDelta func(string)) (anthropicResponse, error) {
+	httpResp, err := doPost(ctx, p.client, p.baseURL+"/v1/messages", headers, body)
+	if err != nil {
+		return anthropicResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp anthropicResponse
+	var inputs []strings.Builder
+	scanner := bufio.NewScanner(httpResp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return anthropicResponse{}, fmt.Errorf("decode stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			resp.Model = event.Message.Model
+		case "content_block_start":
+			for len(resp.Content) <= event.Index {
+				resp.Content = append(resp.Content, anthropicBlock{})
+				inputs = append(inputs, strings.Builder{})
+			}
+			resp.Content[event.Index] = event.ContentBlock
+		case "content_block_delta":
+			if event.Index >= len(resp.Content) {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				resp.Content[event.Index].Text += event.Delta.Text
+				onDelta(event.Delta.Text)
+			case "input_json_delta":
+				inputs[event.Index].WriteString(event.Delta.PartialJSON)
+			}
+		case "error":
+			return anthropicResponse{}, fmt.Errorf("stream error: %s: %s", event.Error.Type, event.Error.Message)
+		case "message_stop":
+			for i := range resp.Content {
+				if resp.Content[i].Type == "tool_use" && inputs[i].Len() > 0 {
+					resp.Content[i].Input = json.RawMessage(inputs[i].String())
+				}
+			}
+			return resp, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return anthropicResponse{}, fmt.Errorf("read stream: %w", err)
+	}
+
+	return anthropicResponse{}, fmt.Errorf("stream ended before message_stop")
+}
+
+// toAnthropicMessages converts OpenAI messages to a system prompt and Anthropic messages.
+// System messages are joined into the system prompt, tool results become user tool_result blocks,
+// and consecutive messages of the same role are merged as the API requires alternating roles.
+func toAnthropicMessages(messages []openai.ChatCompletionMessageParamUnion) (string, []anthropicMessage) {
+	var system []string
+	var out []anthropicMessage
+
+	appendBlocks := func(role string, blocks ...anthropicBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			return
+		}
+		out = append(out, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages {
+		switch {
+		case msg.OfSystem != nil:
+			system = append(system, msg.OfSystem.Content.OfString.Value)
+		case msg.OfUser != nil:
+			text, images := userContent(msg.OfUser)
+			// The API rejects empty text blocks, which a message of only images would otherwise have.
+			var blocks []anthropicBlock
+			if text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: text})
+			}
+			for _, img := range images {
+				blocks = append(blocks, anthropicBlock{Type: "image", Source: &anthropicImage{Type: "base64", MediaType: img.MimeType, Data: img.Data}})
+			}
+			appendBlocks("user", blocks...)
+		case msg.OfAssistant != nil:
+			var blocks []anthropicBlock
+			if content := msg.OfAssistant.Content.OfString.Value; content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: content})
+			}
+			for _, call := range msg.OfAssistant.ToolCalls {
+				if call.OfFunction == nil {
+					continue
+				}
+				blocks = append(blocks, anthropicBlock{
+					Type:  "tool_use",
+					ID:    call.OfFunction.ID,
+					Name:  call.OfFunction.Function.Name,
+					Input: rawArguments(call.OfFunction.Function.Arguments),
+				})
+			}
+			appendBlocks("assistant", blocks...)
+		case msg.OfTool != nil:
+			appendBlocks("user", anthropicBlock{
+				Type:      "tool_result",
+				ToolUseID: msg.OfTool.ToolCallID,
+				Content:   msg.OfTool.Content.OfString.Value,
+			})
+		}
+	}
+
+	return strings.Join(system, "\n\n"), out
+}
+
+// toAnthropicTools converts OpenAI tool definitions to Anthropic tool definitions.
+func toAnthropicTools(tools []openai.ChatCompletionToolUnionParam) []anthropicTool {
+	out := make([]anthropicTool, 0, len(tools))
+	for _, t := range tools {
+		if t.OfFunction == nil {
+			continue
+		}
+		schema := map[string]any(t.OfFunction.Function.Parameters)
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		out = append(out, anthropicTool{
+			Name:        t.OfFunction.Function.Name,
+			Description: t.OfFunction.Function.Description.Value,
+			InputSchema: schema,
+		})
+	}
+	return out
+}
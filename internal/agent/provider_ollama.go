@@ -0,0 +1,215 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ollamaProvider talks to Ollama's native /api/chat endpoint.
+type ollamaProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// ollamaChatRequest is the request body of /api/chat.
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+// ollamaChatResponse is the response body of /api/chat, or a single chunk when streaming.
+type ollamaChatResponse struct {
+	Model   string        `json:"model"`
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// ollamaMessage is a single chat message in Ollama's format.
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+	Images    []string         `json:"images,omitempty"` // Base64-encoded images
+}
+
+// ollamaToolCall is a tool call requested by the model. Ollama sends arguments as an object and has no call IDs.
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaTool is a tool definition in Ollama's format.
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Parameters  map[string]any `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+// newOllamaProvider creates a native Ollama provider. A trailing /v1 in the base URL is ignored.
+func newOllamaProvider(baseURL string) *ollamaProvider {
+	return &ollamaProvider{
+		baseURL: trimAPIVersion(baseURL),
+		client:  &http.Client{},
+	}
+}
+
+// Name identifies the provider in logs.
+func (p *ollamaProvider) Name() string {
+	return ProviderOllama
+}
+
+// Complete sends the request to /api/chat and converts the reply to the OpenAI message format.
+func (p *ollamaProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	body := ollamaChatRequest{
+		Model:    req.Model,
+		Messages: toOllamaMessages(req.Messages),
+		Tools:    toOllamaTools(req.Tools),
+		Stream:   req.OnDelta != nil,
+		Options:  map[string]any{"temperature": req.Temperature},
+	}
+
+	var resp ollamaChatResponse
+	var err error
+	if body.Stream {
+		resp, err = p.stream(ctx, body, req.OnDelta)
+	} else {
+		err = postJSON(ctx, p.client, p.baseURL+"/api/chat", nil, body, &resp)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ollama chat: %w", err)
+	}
+
+	// Ollama has no tool call IDs, so generate ones that stay unique across the session.
+	msg := openai.ChatCompletionMessage{Content: resp.Message.Content}
+	callPrefix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	for i, call := range resp.Message.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, openai.ChatCompletionMessageToolCallUnion{
+			ID:   fmt.Sprintf("call_%s_%d", callPrefix, i),
+			Type: "function",
+			Function: openai.ChatCompletionMessageFunctionToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: string(rawArguments(string(call.Function.Arguments))),
+			},
+		})
+	}
+
+	return &CompletionResponse{Message: msg, Model: resp.Model}, nil
+}
+
+// stream reads the newline-delimited JSON stream of /api/chat, forwarding content deltas
+// and merging the chunks into a single response.
+func (p *ollamaProvider) stream(ctx context.Context, body ollamaChatRequest, onDelta func(string)) (ollamaChatResponse, error) {
+	httpResp, err := doPost(ctx, p.client, p.baseURL+"/api/chat", nil, body)
+	if err != nil {
+		return ollamaChatResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	var merged ollamaChatResponse
+	var content strings.Builder
+	decoder := json.NewDecoder(httpResp.Body)
+	for {
+		var chunk ollamaChatResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return ollamaChatResponse{}, fmt.Errorf("decode stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return ollamaChatResponse{}, fmt.Errorf("stream error: %s", chunk.Error)
+		}
+
+		if chunk.Model != "" {
+			merged.Model = chunk.Model
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			onDelta(chunk.Message.Content)
+		}
+		merged.Message.ToolCalls = append(merged.Message.ToolCalls, chunk.Message.ToolCalls...)
+		if chunk.Done {
+			merged.Done = true
+			break
+		}
+	}
+
+	merged.Message.Role = "assistant"
+	merged.Message.Content = content.String()
+	return merged, nil
+}
+
+// toOllamaMessages converts OpenAI messages to Ollama messages. Tool results are matched to their tool by name.
+func toOllamaMessages(messages []openai.ChatCompletionMessageParamUnion) []ollamaMessage {
+	names := toolCallNames(messages)
+	out := make([]ollamaMessage, 0, len(messages))
+
+	for _, msg := range messages {
+		switch {
+		case msg.OfSystem != nil:
+			out = append(out, ollamaMessage{Role: "system", Content: msg.OfSystem.Content.OfString.Value})
+		case msg.OfUser != nil:
+			text, images := userContent(msg.OfUser)
+			m := ollamaMessage{Role: "user", Content: text}
+			for _, img := range images {
+				m.Images = append(m.Images, img.Data)
+			}
+			out = append(out, m)
+		case msg.OfAssistant != nil:
+			m := ollamaMessage{Role: "assistant", Content: msg.OfAssistant.Content.OfString.Value}
+			for _, call := range msg.OfAssistant.ToolCalls {
+				if call.OfFunction == nil {
+					continue
+				}
+				var tc ollamaToolCall
+				tc.Function.Name = call.OfFunction.Function.Name
+				tc.Function.Arguments = rawArguments(call.OfFunction.Function.Arguments)
+				m.ToolCalls = append(m.ToolCalls, tc)
+			}
+			out = append(out, m)
+		case msg.OfTool != nil:
+			out = append(out, ollamaMessage{
+				Role:     "tool",
+				Content:  msg.OfTool.Content.OfString.Value,
+				ToolName: names[msg.OfTool.ToolCallID],
+			})
+		}
+	}
+
+	return out
+}
+
+// toOllamaTools converts OpenAI tool definitions to Ollama tool definitions.
+func toOllamaTools(tools []openai.ChatCompletionToolUnionParam) []ollamaTool {
+	out := make([]ollamaTool, 0, len(tools))
+	for _, t := range tools {
+		if t.OfFunction == nil {
+			continue
+		}
+		var tool ollamaTool
+		tool.Type = "function"
+		tool.Function.Name = t.OfFunction.Function.Name
+		tool.Function.Description = t.OfFunction.Function.Description.Value
+		tool.Function.Parameters = t.OfFunction.Function.Parameters
+		out = append(out, tool)
+	}
+	return out
+}
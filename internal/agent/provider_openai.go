@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// openAIProvider talks to any OpenAI-compatible chat completions endpoint (OpenAI, Ollama /v1, vLLM, ...).
+type openAIProvider struct {
+	client *openai.Client
+}
+
+// newOpenAIProvider creates an OpenAI-compatible provider for the given endpoint.
+// SDK retries are disabled because the agent retries with its own backoff and fallback chain.
+func newOpenAIProvider(apiKey, baseURL string) *openAIProvider {
+	client := openai.NewClient(
+		option.WithAPIKey(apiKey),
+		option.WithBaseURL(baseURL),
+		option.WithMaxRetries(0),
+	)
+	return &openAIProvider{client: &client}
+}
+
+// Name identifies the provider in logs.
+func (p *openAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+// Complete sends the request to the chat completions endpoint.
+func (p *openAIProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	params := openai.ChatCompletionNewParams{
+		Model:       req.Model,
+		Messages:    req.Messages,
+		Temperature: openai.Float(req.Temperature),
+	}
+	if len(req.Tools) > 0 {
+		params.Tools = req.Tools
+	}
+
+	if req.OnDelta != nil {
+		return p.stream(ctx, params, req.OnDelta)
+	}
+
+	resp, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("LLM returned no choices")
+	}
+
+	return &CompletionResponse{Message: resp.Choices[0].Message, Model: resp.Model}, nil
+}
+
+// stream sends the request as a streaming completion, forwarding content deltas and accumulating the final message.
+func (p *openAIProvider) stream(ctx context.Context, params openai.ChatCompletionNewParams, onDelta func(string)) (*CompletionResponse, error) {
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	var acc openai.ChatCompletionAccumulator
+	for stream.Next() {
+		chunk := stream.Current()
+		if !acc.AddChunk(chunk) {
+			return nil, fmt.Errorf("malformed stream chunk")
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	if len(acc.Choices) == 0 {
+		return nil, fmt.Errorf("LLM returned no choices")
+	}
+
+	return &CompletionResponse{Message: acc.Choices[0].Message, Model: acc.Model}, nil
+}
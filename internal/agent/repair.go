@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseArguments decodes the arguments of a tool call and validates them against the tool schema.
+// Common mistakes of smaller models are repaired first: markdown fences, trailing commas, double-encoded
+// objects, stringified arrays and scalar/array shape mismatches. It returns the arguments re-encoded as JSON
+// and whether anything had to be repaired.
+func parseArguments(schema map[string]any, arguments string) (json.RawMessage, bool, error) {
+	text := strings.TrimSpace(arguments)
+	if text == "" {
+		text = "{}"
+	}
+
+	repaired := false
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		fixed := repairJSON(text)
+		if fixErr := json.Unmarshal([]byte(fixed), &value); fixErr != nil {
+			return nil, false, fmt.Errorf("arguments are not valid JSON: %v", err)
+		}
+		repaired = true
+	}
+
+	// Some models encode the whole argument object as a JSON string.
+	if s, ok := value.(string); ok {
+		var inner map[string]any
+		if err := json.Unmarshal([]byte(s), &inner); err == nil {
+			value = inner
+			repaired = true
+		}
+	}
+
+	if schema != nil {
+		var changed bool
+		value, changed = coerceValue(schema, value)
+		repaired = repaired || changed
+	}
+
+	if err := validateArgs(schema, value); err != nil {
+		return nil, repaired, fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, repaired, fmt.Errorf("encode arguments: %v", err)
+	}
+	return raw, repaired, nil
+}
+
+// repairJSON strips markdown code fences and removes trailing commas before closing brackets.
+func repairJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+
+	var out strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			out.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		if c == '"' {
+			inString = true
+		}
+		if c == ',' {
+			j := i + 1
+			for j < len(text) && strings.IndexByte(" \t\r\n", text[j]) >= 0 {
+				j++
+			}
+			if j < len(text) && (text[j] == '}' || text[j] == ']') {
+				continue
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// coerceValue nudges a decoded value towards the shape the schema expects and reports whether it changed.
+// Values that cannot be coerced are returned as-is for validation to report.
+func coerceValue(schema map[string]any, value any) (any, bool) {
+	typ, _ := schema["type"].(string)
+
+	switch typ {
+	case "object":
+		if s, ok := value.(string); ok {
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(s), &obj); err == nil {
+				value, _ = coerceValue(schema, obj)
+				return value, true
+			}
+		}
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return value, false
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		changed := false
+		for name, v := range obj {
+			propSchema, ok := properties[name].(map[string]any)
+			if !ok {
+				continue
+			}
+			if coerced, c := coerceValue(propSchema, v); c {
+				obj[name] = coerced
+				changed = true
+			}
+		}
+		return obj, changed
+
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		changed := false
+		list, ok := value.([]any)
+		if !ok {
+			switch v := value.(type) {
+			case nil:
+				return value, false
+			case string:
+				// A stringified array, or a single value where a list was expected.
+				var parsed []any
+				if err := json.Unmarshal([]byte(v), &parsed); err == nil && strings.HasPrefix(strings.TrimSpace(v), "[") {
+					list = parsed
+				} else {
+					list = []any{v}
+				}
+			default:
+				list = []any{v}
+			}
+			changed = true
+		}
+		if items != nil {
+			for i, item := range list {
+				if coerced, c := coerceValue(items, item); c {
+					list[i] = coerced
+					changed = true
+				}
+			}
+		}
+		return list, changed
+
+	case "string", "integer", "number", "boolean":
+		// A single-element array where a scalar was expected.
+		if list, ok := value.([]any); ok && len(list) == 1 {
+			coerced, _ := coerceValue(schema, list[0])
+			return coerced, true
+		}
+		s, ok := value.(string)
+		if !ok {
+			return value, false
+		}
+		switch typ {
+		case "integer", "number":
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return f, true
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, true
+			}
+		}
+	}
+
+	return value, false
+}
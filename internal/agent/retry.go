@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/Shreehari-Acharya/vayuu/config"
+)
+
+// newBackends creates the fallback chain: the primary model followed by the configured fallbacks.
+func newBackends(cfg *config.Config) ([]backend, error) {
+	endpoints := append([]config.ModelEndpoint{cfg.PrimaryModel()}, cfg.FallbackModels...)
+
+	backends := make([]backend, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		provider, err := NewProvider(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend{provider: provider, model: endpoint.Model, vision: endpoint.Vision})
+	}
+	return backends, nil
+}
+
+// complete sends a request down the fallback chain and returns the first successful response.
+// Each model is retried with backoff on transient errors before moving on to the next one.
+// When onText is set the response is streamed and onText receives the text produced so far by the current attempt.
+// It also returns the backend that answered, taken from the chain the request went down.
+func (a *Agent) complete(ctx context.Context, req CompletionRequest, onText func(text string)) (*CompletionResponse, responder, error) {
+	var lastErr error
+	messages := req.Messages
+	backends := a.chain()
+
+	for i, b := range backends {
+		req.Model = b.model
+		req.Messages = messages
+		if !b.vision {
+			req.Messages = withoutImages(messages)
+		}
+		resp, err := a.completeWithRetry(ctx, b, req, onText)
+		if err == nil {
+			if resp.Model == "" {
+				resp.Model = b.model
+			}
+			return resp, responder{backend: b, fallback: i > 0}, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return nil, responder{}, ctx.Err()
+		}
+
+		var llmErr *LLMError
+		if errors.As(err, &llmErr) && llmErr.Kind == ErrorContextLength {
+			return nil, responder{}, err
+		}
+
+		if i+1 < len(backends) {
+			next := backends[i+1]
+			slog.Warn("falling back to next model", "failed", b.model, "next", next.model, "provider", next.provider.Name(), "error", err)
+		}
+	}
+
+	return nil, responder{}, lastErr
+}
+
+// completeWithRetry calls a single backend, retrying retryable errors with exponential backoff and jitter.
+// A Retry-After longer than maxRetryAfter gives up on the backend so the chain can move on.
+func (a *Agent) completeWithRetry(ctx context.Context, b backend, req CompletionRequest, onText func(string)) (*CompletionResponse, error) {
+	for attempt := 1; ; attempt++ {
+		if onText != nil {
+			// Start from empty text on every attempt so a retried stream doesn't repeat partial output.
+			var streamed strings.Builder
+			onText("")
+			req.OnDelta = func(delta string) {
+				streamed.WriteString(delta)
+				onText(streamed.String())
+			}
+		}
+
+		resp, err := b.provider.Complete(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		llmErr := classifyError(err, b.provider.Name(), b.model)
+		if !llmErr.Retryable() || attempt >= maxConsecutiveLLMErrors || llmErr.RetryAfter > maxRetryAfter {
+			slog.Error("LLM request failed", "provider", llmErr.Provider, "model", llmErr.Model, "kind", llmErr.Kind, "status", llmErr.StatusCode, "attempt", attempt, "error", err)
+			return nil, llmErr
+		}
+
+		delay := backoffDelay(attempt, llmErr.RetryAfter)
+		slog.Warn("LLM request failed, retrying",
+			"provider", llmErr.Provider,
+			"model", llmErr.Model,
+			"kind", llmErr.Kind,
+			"status", llmErr.StatusCode,
+			"attempt", attempt,
+			"max", maxConsecutiveLLMErrors,
+			"delay", delay,
+			"error", err,
+		)
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoffDelay returns the wait before the next attempt: exponential backoff with equal jitter,
+// raised to the server's Retry-After when that is longer.
+func backoffDelay(attempt int, retryAfter time.Duration) time.Duration {
+	ceiling := retryBaseDelay << (attempt - 1)
+	if ceiling > retryMaxDelay || ceiling <= 0 {
+		ceiling = retryMaxDelay
+	}
+
+	delay := ceiling/2 + time.Duration(rand.Int64N(int64(ceiling/2)+1))
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// validateArgs checks decoded JSON arguments against the subset of JSON Schema used by tool definitions:
+// type, properties, required, additionalProperties, items and enum.
+func validateArgs(schema map[string]any, value any) error {
+	if schema == nil {
+		return nil
+	}
+	var problems []string
+	validateValue(schema, value, "arguments", &problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateValue appends a description of every mismatch between value and schema to problems.
+func validateValue(schema map[string]any, value any, at string, problems *[]string) {
+	if typ, ok := schema["type"].(string); ok && !matchesType(typ, value) {
+		*problems = append(*problems, fmt.Sprintf("%s must be %s, got %s", at, withArticle(typ), describeType(value)))
+		return
+	}
+
+	if enum, ok := schema["enum"]; ok && !inEnum(enum, value) {
+		*problems = append(*problems, fmt.Sprintf("%s must be one of %v", at, enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s is required", at, name))
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			propSchema, ok := properties[name].(map[string]any)
+			if !ok {
+				if allowed, set := schema["additionalProperties"].(bool); set && !allowed {
+					*problems = append(*problems, fmt.Sprintf("%s.%s is not a known property", at, name))
+				}
+				continue
+			}
+			validateValue(propSchema, v[name], at+"."+name, problems)
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", at, i), problems)
+			}
+		}
+	}
+}
+
+// matchesType reports whether a decoded JSON value has the given JSON Schema type.
+func matchesType(typ string, value any) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+// describeType names the JSON type of a decoded value for error messages.
+func describeType(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// withArticle prefixes a JSON Schema type name with an indefinite article.
+func withArticle(typ string) string {
+	switch typ {
+	case "object", "array", "integer":
+		return "an " + typ
+	case "null":
+		return typ
+	default:
+		return "a " + typ
+	}
+}
+
+// inEnum reports whether value equals one of the enum entries.
+func inEnum(enum any, value any) bool {
+	switch values := enum.(type) {
+	case []any:
+		for _, candidate := range values {
+			if candidate == value {
+				return true
+			}
+		}
+	case []string:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		for _, candidate := range values {
+			if candidate == s {
+				return true
+			}
+		}
+	default:
+		return true
+	}
+	return false
+}
+
+// schemaStrings reads a list of strings from a schema keyword, which may be declared as []string or []any.
+func schemaStrings(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
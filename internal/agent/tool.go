@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// TextResult returns a successful tool result with the given content.
+func TextResult(content string) ToolResult {
+	return ToolResult{Content: content}
+}
+
+// ErrorResult returns a failed tool result with a formatted message.
+func ErrorResult(format string, args ...any) ToolResult {
+	return ToolResult{Content: fmt.Sprintf(format, args...), IsError: true}
+}
+
+// TypedHandler adapts a handler taking decoded arguments of type T to a ToolFunc.
+// Arguments are validated against the tool schema before they are decoded, so T only needs json tags.
+func TypedHandler[T any](fn func(ctx context.Context, args T) ToolResult) ToolFunc {
+	return func(ctx context.Context, raw json.RawMessage) ToolResult {
+		var args T
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return ErrorResult("invalid arguments: %v", err)
+		}
+		return fn(ctx, args)
+	}
+}
+
+// modelContent renders the result as the tool message sent back to the model.
+func (r ToolResult) modelContent() string {
+	if r.IsError {
+		return "error: " + r.Content
+	}
+	return r.Content
+}
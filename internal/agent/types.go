@@ -1,27 +1,188 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Shreehari-Acharya/vayuu/config"
 	"github.com/Shreehari-Acharya/vayuu/internal/memory"
+	"github.com/Shreehari-Acharya/vayuu/internal/policy"
 	"github.com/openai/openai-go/v3"
 )
 
 type Agent struct {
-	client       *openai.Client
-	model        string
+	cfg          *config.Config
+	backendsMu   sync.RWMutex // Guards backends and budget, as the model can be switched at runtime
+	backends     []backend
 	tools        map[string]Tool
+	toolsMu      sync.Mutex // Guards the tools cache, as chats run concurrently
 	toolsCache   []openai.ChatCompletionToolUnionParam
 	toolsDirty   bool
 	systemPrompt string
 	workDir      string
 	memoryWriter memory.MemoryWriter
 	memoryMgr    *memory.MemoryManager
+	sessions     *memory.SessionStore
+	budget       tokenBudget
+	maxParallel  int // Upper bound on tool calls running at once
+	maxImageDim  int // Images are downscaled so neither side exceeds this many pixels
+
+	policy          *policy.Engine // Decides which tool calls need the user's approval
+	approvalTimeout time.Duration  // How long a call waits for approval
+}
+
+// backend is one entry of the model fallback chain.
+type backend struct {
+	provider Provider
+	model    string
+	vision   bool // The model accepts images
+}
+
+// responder is the backend that answered a request. It is a copy, so it stays valid when the model is switched.
+type responder struct {
+	backend
+	fallback bool // The backend is one of the fallbacks rather than the primary model
+}
+
+// Input is a user message for the agent.
+type Input struct {
+	Text   string
+	Images []string // Absolute paths of images to show the model, if it is vision-capable
+}
+
+// Reply is the outcome of a successful agent run.
+type Reply struct {
+	Text     string
+	Model    string // Model that produced the final answer; only set when fallback models are configured
+	Fallback bool   // Whether a fallback model answered instead of the primary one
+
+	Attachments []Attachment // Files produced by tools for the user, in the order they were requested
+	Stopped     bool         // The run was cancelled; Text summarizes what was completed before the stop
+}
+
+// Origin identifies the chat a run belongs to and the channel it came through, e.g. a Telegram chat.
+type Origin struct {
+	Channel string // Name of the channel, e.g. "telegram"
+	Chat    string // ID of the chat within the channel
+	User    string // ID of the user whose message started the run, within the channel
+}
+
+// Scope isolates a run on behalf of a user: whose memory it uses, where its tools work and which tools it may call.
+type Scope struct {
+	Namespace string                 // Memory namespace; empty uses the shared memory
+	WorkDir   string                 // Workspace of the run's tools; empty uses the agent workspace
+	AllowTool func(name string) bool // nil allows every tool
+	Confined  bool                   // Keeps tools out of the home directory: ~/ paths are refused and calls touching it need an admin's approval
+}
+
+// ApprovalRequest describes a tool call the policy holds back until the user approves it.
+type ApprovalRequest struct {
+	Tool      string
+	Summary   string // What the call does: its commands, or its arguments
+	Reason    string // Why the policy asks
+	AdminOnly bool   // Only an admin may approve it, not the user whose run made the call
+}
+
+// ApproveFunc asks the user to approve a tool call. It blocks until the user answers or ctx is done,
+// in which case it returns the context's error.
+type ApproveFunc func(ctx context.Context, req ApprovalRequest) (bool, error)
+
+// EventKind identifies a progress event emitted during an agent run.
+type EventKind string
+
+// Event reports progress of an agent run so callers can render it live.
+type Event struct {
+	Kind EventKind
+	Text string // Response text streamed so far, for EventText
+	Tool string // Tool name, for EventToolStart and EventToolEnd
+}
+
+// EventFunc receives progress events. It should return quickly and be safe for concurrent use,
+// as tool events arrive from parallel tool calls.
+type EventFunc func(Event)
+
+// conversation holds the messages of a single agent run.
+// messages is what gets sent to the model and may be compacted; turn records
+// everything added during the run, in order, for the memory log.
+type conversation struct {
+	messages []openai.ChatCompletionMessageParamUnion
+	turn     []openai.ChatCompletionMessageParamUnion
+	backend  responder // Backend that produced the last response
+	onEvent  EventFunc // Optional progress listener
+
+	attachments []Attachment // Files collected from tool results during the run
+	steps       []toolStep   // Tool calls completed during the run, in order
+}
+
+// toolStep records the outcome of a tool call for the summary of a stopped run.
+type toolStep struct {
+	name        string
+	failed      bool
+	interrupted bool // Failed because the run was cancelled while it was running
+}
+
+// tokenBudget controls how much history and tool output is sent to the model.
+type tokenBudget struct {
+	window         int     // Model context window in tokens
+	limit          int     // Prompt size that triggers compaction
+	charsPerToken  float64 // Average characters per token for estimation
+	maxResultChars int     // Tool results above this size are spilled to the workspace
+}
+
+// modelProfile describes the context limits of a family of models.
+type modelProfile struct {
+	match         string
+	contextWindow int
+	charsPerToken float64
+}
+
+// ToolFunc handles a tool call. args holds the call's JSON arguments, already validated against the tool's Parameters.
+// The context is cancelled when the run is stopped or the process shuts down.
+type ToolFunc func(ctx context.Context, args json.RawMessage) ToolResult
+
+// ToolResult is the structured outcome of a tool call.
+type ToolResult struct {
+	Content     string         // Text returned to the model
+	IsError     bool           // Whether the call failed; the model sees the content as an error
+	Attachments []Attachment   // Files to deliver to the user along with the reply
+	Images      []string       // Absolute paths of images to show the model after the result
+	Metadata    map[string]any // Extra details for logs; not sent to the model
+}
+
+// Attachment is a file a tool wants delivered to the user.
+type Attachment struct {
+	Path    string // Absolute path of the file
+	Caption string
+	Type    string // Optional type hint: image, video or doc
+}
+
+// ToolAccess describes what a single tool call touches, so independent calls can run in parallel.
+// Two calls conflict when either is exclusive or one writes a path the other reads or writes.
+type ToolAccess struct {
+	Exclusive bool     // Must not overlap with any other call, e.g. arbitrary shell commands
+	Reads     []string // Absolute paths read by the call
+	Writes    []string // Absolute paths created, modified or deleted by the call
+	Commands  []string // Shell commands run by the call, checked by the policy
 }
 
-type ToolFunc func(args map[string]any) string
+// AccessFunc reports the access of a tool call from its arguments.
+type AccessFunc func(ctx context.Context, args map[string]any) ToolAccess
 
 type Tool struct {
 	Name        string
 	Description string
 	Parameters  map[string]any
 	Handler     ToolFunc
+	Access      AccessFunc // Optional; tools without it always run on their own
+}
+
+// toolRun tracks one tool call while a batch is dispatched.
+type toolRun struct {
+	call   openai.ChatCompletionMessageToolCallUnion
+	access ToolAccess
+	deps   []*toolRun    // Earlier calls in the batch this one conflicts with
+	done   chan struct{} // Closed once the call has finished
+	result ToolResult
 }
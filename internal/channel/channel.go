@@ -0,0 +1,79 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
+// NewHub creates a hub for the given channels, whose names must be unique.
+func NewHub(channels ...Channel) (*Hub, error) {
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("no channel is enabled")
+	}
+
+	h := &Hub{byName: make(map[string]Channel, len(channels))}
+	for _, ch := range channels {
+		if _, exists := h.byName[ch.Name()]; exists {
+			return nil, fmt.Errorf("channel %q: enabled twice", ch.Name())
+		}
+		h.byName[ch.Name()] = ch
+		h.channels = append(h.channels, ch)
+	}
+	return h, nil
+}
+
+// Run starts every channel and waits until they have all stopped. If one of them fails, the others are stopped
+// too and its error is returned.
+func (h *Hub) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for _, ch := range h.channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("channel starting", "channel", ch.Name())
+			if err := ch.Start(ctx); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("channel %s: %w", ch.Name(), err)
+					cancel()
+				})
+				return
+			}
+			slog.Info("channel stopped", "channel", ch.Name())
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Channel returns the enabled channel with the given name.
+func (h *Hub) Channel(name string) (Channel, bool) {
+	ch, ok := h.byName[name]
+	return ch, ok
+}
+
+// Notify sends Markdown text to the chat a run came from.
+func (h *Hub) Notify(ctx context.Context, origin agent.Origin, text string) error {
+	ch, ok := h.Channel(origin.Channel)
+	if !ok {
+		return fmt.Errorf("channel %q is not enabled", origin.Channel)
+	}
+	return ch.SendText(ctx, origin.Chat, text)
+}
+
+// Wake runs the agent in the chat a run came from, on behalf of the user who started that run.
+func (h *Hub) Wake(ctx context.Context, origin agent.Origin, text string) error {
+	ch, ok := h.Channel(origin.Channel)
+	if !ok {
+		return fmt.Errorf("channel %q is not enabled", origin.Channel)
+	}
+	return ch.Wake(ctx, origin.Chat, origin.User, text)
+}
@@ -0,0 +1,29 @@
+package channel
+
+import "context"
+
+// Channel is a front end users talk to the agent through, such as a Telegram bot.
+// A channel receives messages and runs the agent for them with agent.WithOrigin. During a run it talks to the agent
+// through channel-neutral hooks: progress arrives as agent events, approvals are asked for by the agent.ApproveFunc
+// it sets with agent.WithApprover, and the reply comes back with the files to deliver as its attachments.
+// The outbound methods reach a chat outside of a run, e.g. to report on work that finished in the background.
+type Channel interface {
+	// Name identifies the channel in config and in conversation keys, e.g. "telegram".
+	Name() string
+
+	// Start receives messages until ctx is done. It returns an error if the channel can't run.
+	Start(ctx context.Context) error
+
+	// SendText sends Markdown text to a chat, formatted as well as the channel allows.
+	SendText(ctx context.Context, chat, text string) error
+
+	// Wake runs the agent on text in a chat on behalf of a user, as if they had sent it, and delivers the reply.
+	// It returns once the run is scheduled.
+	Wake(ctx context.Context, chat, user, text string) error
+}
+
+// Hub runs the enabled channels side by side and routes outbound messages to them by name.
+type Hub struct {
+	channels []Channel
+	byName   map[string]Channel
+}
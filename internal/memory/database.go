@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
@@ -112,6 +113,41 @@ func (d *Database) GetAllProfile() (map[string]string, error) {
 	return result, nil
 }
 
+// ProfileEntry is a profile key-value pair along with its row ID.
+type ProfileEntry struct {
+	ID        int64
+	Key       string
+	Value     string
+	UpdatedAt time.Time
+}
+
+// ListProfile returns all profile entries sorted by key.
+func (d *Database) ListProfile() ([]ProfileEntry, error) {
+	rows, err := d.db.Query("SELECT rowid, key, value, updated_at FROM user_profile ORDER BY key")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ProfileEntry
+	for rows.Next() {
+		var e ProfileEntry
+		var updatedAt string
+		if err := rows.Scan(&e.ID, &e.Key, &e.Value, &updatedAt); err != nil {
+			return nil, err
+		}
+		e.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// DeleteProfile removes a profile entry by row ID.
+func (d *Database) DeleteProfile(id int64) error {
+	_, err := d.db.Exec("DELETE FROM user_profile WHERE rowid = ?", id)
+	return err
+}
+
 // Preference represents a user preference with confidence score.
 // Confidence increases with each mention (0.0 to 1.0).
 type Preference struct {
@@ -172,6 +208,17 @@ func (d *Database) queryPreferences(where string, args ...any) ([]Preference, er
 	return result, nil
 }
 
+// DeletePreference removes a preference by ID.
+func (d *Database) DeletePreference(id int) error {
+	_, err := d.db.Exec("DELETE FROM preferences WHERE id = ?", id)
+	return err
+}
+
+// Ping checks that the database is reachable.
+func (d *Database) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
 // IncrementTopic increments the mention count for a topic.
 // Creates the topic if it doesn't exist.
 func (d *Database) IncrementTopic(name string) error {
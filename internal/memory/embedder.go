@@ -89,3 +89,22 @@ func (e *Embedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32,
 	}
 	return embeddings, nil
 }
+
+// Ping checks that Ollama is reachable.
+func (e *Embedder) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", e.baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -6,28 +6,22 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/Shreehari-Acharya/vayuu/config"
 	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/option"
 )
 
+// ChatFunc sends a system and user prompt to an LLM and returns its text reply.
+// The agent provides one backed by its configured provider.
+type ChatFunc func(ctx context.Context, system, prompt string) (string, error)
+
 // FactExtractor uses an LLM to extract structured facts from conversations.
 // It analyzes conversation text and returns facts, preferences, and topics.
 type FactExtractor struct {
-	client *openai.Client // LLM client
-	model  string         // Model name for extraction
+	chat ChatFunc // LLM used for extraction
 }
 
-// NewFactExtractor creates a new FactExtractor using the provided config.
-func NewFactExtractor(cfg *config.Config) *FactExtractor {
-	client := openai.NewClient(
-		option.WithAPIKey(cfg.ApiKey),
-		option.WithBaseURL(cfg.ApiBaseURL),
-	)
-	return &FactExtractor{
-		client: &client,
-		model:  cfg.Model,
-	}
+// NewFactExtractor creates a new FactExtractor that sends prompts through chat.
+func NewFactExtractor(chat ChatFunc) *FactExtractor {
+	return &FactExtractor{chat: chat}
 }
 
 // ExtractedFact represents a structured fact extracted from conversation.
@@ -61,24 +55,16 @@ Rules:
 Conversation:
 ` + conversation
 
-	resp, err := e.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model: e.model,
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			newSystemMsg(`You extract structured facts from conversations. Always respond with valid JSON array.`),
-			newUserMsg(prompt),
-		},
-		Temperature: openai.Float(0.3),
-	})
+	content, err := e.chat(ctx, `You extract structured facts from conversations. Always respond with valid JSON array.`, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("LLM fact extraction failed: %w", err)
 	}
 
-	if len(resp.Choices) == 0 {
+	if content == "" {
 		return nil, fmt.Errorf("no response from LLM")
 	}
 
 	// Parse response, stripping any markdown code blocks
-	content := resp.Choices[0].Message.Content
 	content = strings.TrimSpace(content)
 	content = strings.TrimPrefix(content, "```json")
 	content = strings.TrimPrefix(content, "```")
@@ -2,8 +2,11 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -19,12 +22,14 @@ import (
 type MemoryManager struct {
 	embedder  *Embedder      // Generates embeddings
 	store     *VectorStore   // Vector database
-	database  *Database      // SQLite for structured data
+	database  *Database      // SQLite for structured data of the shared namespace
 	extractor *FactExtractor // LLM for fact extraction
 	config    *Config        // Configuration
+	workDir   string         // Root of per-namespace databases
 
 	mu          sync.RWMutex
-	memoryCount int // Total memories stored
+	memoryCount int                  // Total memories stored
+	namespaces  map[string]*Database // Databases of other namespaces, opened on first use
 }
 
 // NewMemoryManager creates a MemoryManager with vector store only (no database).
@@ -49,7 +54,8 @@ func NewMemoryManager(cfg *Config) (*MemoryManager, error) {
 
 // NewMemoryManagerWithDB creates a MemoryManager with all components:
 // vector store, SQLite database, and fact extractor.
-func NewMemoryManagerWithDB(workDir string, cfg *config.Config) (*MemoryManager, error) {
+// The fact extractor sends its prompts through chat.
+func NewMemoryManagerWithDB(workDir string, cfg *config.Config, chat ChatFunc) (*MemoryManager, error) {
 	// Set defaults for Ollama
 	ollamaURL := cfg.OllamaBaseURL
 	if ollamaURL == "" {
@@ -80,7 +86,7 @@ func NewMemoryManagerWithDB(workDir string, cfg *config.Config) (*MemoryManager,
 		slog.Warn("failed to initialize database", "error", err)
 	}
 
-	extractor := NewFactExtractor(cfg)
+	extractor := NewFactExtractor(chat)
 
 	mgr := &MemoryManager{
 		embedder:  embedder,
@@ -88,6 +94,7 @@ func NewMemoryManagerWithDB(workDir string, cfg *config.Config) (*MemoryManager,
 		database:  db,
 		extractor: extractor,
 		config:    memConfig,
+		workDir:   workDir,
 	}
 
 	slog.Info("memory manager initialized with database")
@@ -119,6 +126,9 @@ func (m *MemoryManager) AddMemory(ctx context.Context, content string, memType M
 	for k, v := range metadata {
 		payload[k] = v
 	}
+	if namespace := NamespaceFrom(ctx); namespace != "" {
+		payload[namespacePayloadKey] = namespace
+	}
 
 	// Store in vector database
 	if err := m.store.Upsert(ctx, id, vector, payload); err != nil {
@@ -144,8 +154,8 @@ func (m *MemoryManager) SearchMemory(ctx context.Context, query string, limit in
 		return nil, fmt.Errorf("generate embedding: %w", err)
 	}
 
-	// Search vector store
-	results, err := m.store.Search(ctx, vector, limit, nil)
+	// Search vector store, restricted to the context's namespace
+	results, err := m.store.Search(ctx, vector, limit, namespaceFilter(NamespaceFrom(ctx)))
 	if err != nil {
 		return nil, fmt.Errorf("search memory: %w", err)
 	}
@@ -163,15 +173,16 @@ func (m *MemoryManager) GetContext(ctx context.Context, query string, maxTokens
 		return "", err
 	}
 
-	if len(results) == 0 && m.database == nil {
+	database := m.databaseFor(NamespaceFrom(ctx))
+	if len(results) == 0 && database == nil {
 		return "", nil
 	}
 
 	var contextParts []string
 
 	// Add user profile from database
-	if m.database != nil {
-		userSummary := m.database.GetUserSummary()
+	if database != nil {
+		userSummary := database.GetUserSummary()
 		if userSummary != "" {
 			contextParts = append(contextParts, "User Profile:\n"+userSummary)
 		}
@@ -226,7 +237,8 @@ func (m *MemoryManager) AddKnowledge(ctx context.Context, knowledge string, meta
 // Stores results in both vector DB and SQLite.
 func (m *MemoryManager) ProcessConversation(ctx context.Context, userInput, assistantResponse string) error {
 	// Skip if no extractor or database
-	if m.extractor == nil || m.database == nil {
+	database := m.databaseFor(NamespaceFrom(ctx))
+	if m.extractor == nil || database == nil {
 		return nil
 	}
 
@@ -249,7 +261,7 @@ func (m *MemoryManager) ProcessConversation(ctx context.Context, userInput, assi
 			}
 			// Also store in SQLite profile
 			if fact.Key != "" && fact.Value != "" {
-				m.database.SetProfile(fact.Key, fact.Value)
+				database.SetProfile(fact.Key, fact.Value)
 			}
 
 		case "preference":
@@ -259,12 +271,12 @@ func (m *MemoryManager) ProcessConversation(ctx context.Context, userInput, assi
 			}
 			// Also store in SQLite
 			if fact.Key != "" && fact.Value != "" {
-				m.database.SetPreference(fact.Key, fact.Value, fact.Category)
+				database.SetPreference(fact.Key, fact.Value, fact.Category)
 			}
 
 		case "topic":
 			// Increment topic count in SQLite
-			m.database.IncrementTopic(fact.Value)
+			database.IncrementTopic(fact.Value)
 			// Store in vector DB
 			if err := m.AddKnowledge(ctx, "Topic: "+fact.Value, map[string]string{"topic": fact.Value}); err != nil {
 				slog.Warn("failed to store topic", "error", err)
@@ -290,5 +302,151 @@ func (m *MemoryManager) Close() error {
 	if m.database != nil {
 		m.database.Close()
 	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, db := range m.namespaces {
+		db.Close()
+	}
 	return nil
 }
+
+// Health checks every memory backend: the vector store, the embedder and the database.
+func (m *MemoryManager) Health(ctx context.Context) []Health {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	health := []Health{
+		{Component: "Qdrant", Err: m.store.Ping(ctx)},
+		{Component: "Ollama embeddings", Err: m.embedder.Ping(ctx)},
+	}
+
+	dbHealth := Health{Component: "SQLite"}
+	if database := m.databaseFor(NamespaceFrom(ctx)); database != nil {
+		dbHealth.Err = database.Ping(ctx)
+	} else {
+		dbHealth.Err = errors.New("not configured")
+	}
+	return append(health, dbHealth)
+}
+
+// KnownFacts lists the profile entries and preferences stored for the context's namespace.
+func (m *MemoryManager) KnownFacts(ctx context.Context) ([]KnownFact, error) {
+	database := m.databaseFor(NamespaceFrom(ctx))
+	if database == nil {
+		return nil, errors.New("memory database is not configured")
+	}
+
+	profile, err := database.ListProfile()
+	if err != nil {
+		return nil, fmt.Errorf("list profile: %w", err)
+	}
+	prefs, err := database.GetAllPreferences()
+	if err != nil {
+		return nil, fmt.Errorf("list preferences: %w", err)
+	}
+
+	facts := make([]KnownFact, 0, len(profile)+len(prefs))
+	for _, e := range profile {
+		facts = append(facts, KnownFact{Kind: KnownProfile, ID: e.ID, Key: e.Key, Value: e.Value})
+	}
+	for _, p := range prefs {
+		facts = append(facts, KnownFact{Kind: KnownPreference, ID: int64(p.ID), Key: p.Key, Value: p.Value, Category: p.Category})
+	}
+	return facts, nil
+}
+
+// Forget deletes a known fact of the context's namespace along with the vector memories stored for its key,
+// so it no longer shows up in the agent's context. It returns the deleted fact.
+func (m *MemoryManager) Forget(ctx context.Context, kind KnownFactKind, id int64) (KnownFact, error) {
+	facts, err := m.KnownFacts(ctx)
+	if err != nil {
+		return KnownFact{}, err
+	}
+
+	var fact KnownFact
+	found := false
+	for _, f := range facts {
+		if f.Kind == kind && f.ID == id {
+			fact, found = f, true
+			break
+		}
+	}
+	if !found {
+		return KnownFact{}, errors.New("already forgotten")
+	}
+
+	// Vector memories go first: if that fails the fact stays listed and can be deleted again.
+	if fact.Key != "" {
+		memType := MemoryTypeFact
+		if kind == KnownPreference {
+			memType = MemoryTypePreference
+		}
+		filter := namespaceFilter(NamespaceFrom(ctx))
+		filter["must"] = append(filter["must"].([]any),
+			map[string]any{"key": "key", "match": map[string]any{"value": fact.Key}},
+			map[string]any{"key": "type", "match": map[string]any{"value": string(memType)}},
+		)
+		if err := m.store.DeleteByFilter(ctx, filter); err != nil {
+			return KnownFact{}, fmt.Errorf("delete vector memories: %w", err)
+		}
+	}
+
+	database := m.databaseFor(NamespaceFrom(ctx))
+	if kind == KnownPreference {
+		err = database.DeletePreference(int(id))
+	} else {
+		err = database.DeleteProfile(id)
+	}
+	if err != nil {
+		return KnownFact{}, fmt.Errorf("delete %s: %w", kind, err)
+	}
+
+	slog.Info("memory forgotten", "kind", kind, "key", fact.Key, "namespace", NamespaceFrom(ctx))
+	return fact, nil
+}
+
+// databaseFor returns the database of a namespace, opening it on first use.
+// The shared namespace uses the main database; others live under the namespace directory.
+func (m *MemoryManager) databaseFor(namespace string) *Database {
+	if namespace == "" || m.database == nil {
+		return m.database
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if db, ok := m.namespaces[namespace]; ok {
+		return db
+	}
+
+	dir := namespaceDir(filepath.Join(m.workDir, MemoryDirName), namespace)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		slog.Warn("failed to create namespace directory", "namespace", namespace, "error", err)
+		return nil
+	}
+	db, err := NewDatabase(dir)
+	if err != nil {
+		slog.Warn("failed to open namespace database", "namespace", namespace, "error", err)
+		return nil
+	}
+
+	if m.namespaces == nil {
+		m.namespaces = make(map[string]*Database)
+	}
+	m.namespaces[namespace] = db
+	return db
+}
+
+// namespaceFilter builds the Qdrant filter matching memories of a namespace.
+// Memories of the shared namespace carry no namespace field.
+func namespaceFilter(namespace string) map[string]any {
+	if namespace == "" {
+		return map[string]any{
+			"must": []any{map[string]any{"is_empty": map[string]any{"key": namespacePayloadKey}}},
+		}
+	}
+	return map[string]any{
+		"must": []any{map[string]any{"key": namespacePayloadKey, "match": map[string]any{"value": namespace}}},
+	}
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -20,15 +21,19 @@ func NewFileMemoryWriter(workDir string) *FileMemoryWriter {
 	}
 }
 
-// Write appends user and assistant messages to a daily JSONL file.
+// Write appends user and assistant messages to a daily JSONL file of the context's memory namespace.
 // Each message is encoded as a JSON object on a single line.
-func (w *FileMemoryWriter) Write(messages []openai.ChatCompletionMessageParamUnion) error {
+func (w *FileMemoryWriter) Write(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) error {
 	if w == nil {
 		return nil
 	}
 	if w.Dir == "" {
 		return fmt.Errorf("memory writer directory is empty")
 	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	if w.Clock == nil {
 		w.Clock = time.Now
 	}
@@ -36,13 +41,15 @@ func (w *FileMemoryWriter) Write(messages []openai.ChatCompletionMessageParamUni
 		w.MaxSize = DefaultMemoryMaxSize
 	}
 
+	dir := namespaceDir(w.Dir, NamespaceFrom(ctx))
+
 	// Ensure directory exists
-	if err := os.MkdirAll(w.Dir, 0755); err != nil {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
 	now := w.Clock()
-	filePath := filepath.Join(w.Dir, fmt.Sprintf("%s.jsonl", now.Format(DayFileLayout)))
+	filePath := filepath.Join(dir, fmt.Sprintf("%s.jsonl", now.Format(DayFileLayout)))
 
 	// Rotate if file too large
 	if err := w.rotateIfNeeded(filePath); err != nil {
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// namespaceKey is the context key under which the memory namespace of a run is stored.
+type namespaceKey struct{}
+
+// WithNamespace returns a context whose memory reads and writes are isolated to the given namespace.
+// The empty namespace is the shared memory used before namespaces existed.
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// NamespaceFrom returns the memory namespace of the context.
+func NamespaceFrom(ctx context.Context) string {
+	namespace, _ := ctx.Value(namespaceKey{}).(string)
+	return namespace
+}
+
+// namespaceDir returns the directory holding a namespace's files below dir.
+func namespaceDir(dir, namespace string) string {
+	if namespace == "" {
+		return dir
+	}
+	return filepath.Join(dir, NamespaceDirName, namespace)
+}
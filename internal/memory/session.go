@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// NewSessionStore creates a SessionStore rooted at the provided work directory.
+// Each conversation is stored in its own JSONL file under the sessions directory.
+func NewSessionStore(workDir string) *SessionStore {
+	return &SessionStore{
+		Dir:         filepath.Join(workDir, SessionDirName),
+		MaxMessages: DefaultSessionMaxMessages,
+		sessions:    make(map[string][]openai.ChatCompletionMessageParamUnion),
+	}
+}
+
+// Load returns the message history of a conversation, reading it from disk on first access.
+// The returned slice is a copy and can be modified freely by the caller.
+func (s *SessionStore) Load(key string) ([]openai.ChatCompletionMessageParamUnion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if history, ok := s.sessions[key]; ok {
+		return append([]openai.ChatCompletionMessageParamUnion(nil), history...), nil
+	}
+
+	history, err := s.readFile(key)
+	if err != nil {
+		return nil, err
+	}
+	s.sessions[key] = history
+
+	return append([]openai.ChatCompletionMessageParamUnion(nil), history...), nil
+}
+
+// Save replaces the message history of a conversation and persists it to disk.
+// History longer than MaxMessages is trimmed from the oldest turn onwards.
+func (s *SessionStore) Save(key string, history []openai.ChatCompletionMessageParamUnion) error {
+	history = trimSession(history, s.MaxMessages)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[key] = append([]openai.ChatCompletionMessageParamUnion(nil), history...)
+	return s.writeFile(key, history)
+}
+
+// Clear drops the message history of a conversation, both in memory and on disk.
+func (s *SessionStore) Clear(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, key)
+	if err := s.migrateLegacy(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove session: %w", err)
+	}
+	return nil
+}
+
+// path returns the session file path for a conversation. Characters that aren't safe in file names, such as the
+// colon between channel and chat, are replaced.
+func (s *SessionStore) path(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, key)
+	return filepath.Join(s.Dir, name+".jsonl")
+}
+
+// readFile loads a session file, returning an empty history if it doesn't exist.
+// A Telegram chat's session saved under its bare chat ID, as before sessions were keyed by channel, is moved to its current name first.
+func (s *SessionStore) readFile(key string) ([]openai.ChatCompletionMessageParamUnion, error) {
+	if err := s.migrateLegacy(key); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open session: %w", err)
+	}
+	defer file.Close()
+
+	var history []openai.ChatCompletionMessageParamUnion
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), int(DefaultMemoryMaxSize))
+	for scanner.Scan() {
+		var entry MemoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("parse session entry: %w", err)
+		}
+		if msg, ok := sessionMessage(entry); ok {
+			history = append(history, msg)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read session: %w", err)
+	}
+
+	return trimSession(history, s.MaxMessages), nil
+}
+
+// migrateLegacy renames the legacy <chatID>.jsonl file of a Telegram conversation to its current name,
+// unless there is no such file or the current one already exists.
+func (s *SessionStore) migrateLegacy(key string) error {
+	chatID, ok := strings.CutPrefix(key, legacySessionPrefix)
+	if !ok {
+		return nil
+	}
+	if _, err := strconv.ParseInt(chatID, 10, 64); err != nil {
+		return nil
+	}
+
+	legacy, path := filepath.Join(s.Dir, chatID+".jsonl"), s.path(key)
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.Rename(legacy, path); err != nil {
+		return fmt.Errorf("migrate session: %w", err)
+	}
+	slog.Info("migrated session file", "from", legacy, "to", path)
+	return nil
+}
+
+// writeFile atomically rewrites a session file with the given history.
+func (s *SessionStore) writeFile(key string, history []openai.ChatCompletionMessageParamUnion) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	path := s.path(key)
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	timestamp := time.Now().Format(ClockLayout)
+	for _, msg := range history {
+		entry, ok := buildSessionEntry(msg, timestamp)
+		if !ok {
+			continue
+		}
+		if err := encoder.Encode(entry); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// buildSessionEntry converts an OpenAI message to a MemoryEntry, keeping tool calls and tool results.
+// Unlike buildMemoryEntry, thinking tags are kept so the model sees its own turns unchanged.
+func buildSessionEntry(msg openai.ChatCompletionMessageParamUnion, timestamp string) (MemoryEntry, bool) {
+	entry := MemoryEntry{Timestamp: timestamp}
+
+	switch {
+	case msg.OfSystem != nil:
+		entry.Role = "system"
+		entry.Content = msg.OfSystem.Content.OfString.Value
+	case msg.OfUser != nil:
+		entry.Role = "user"
+		entry.Content = msg.OfUser.Content.OfString.Value
+	case msg.OfAssistant != nil:
+		entry.Role = "assistant"
+		entry.Content = msg.OfAssistant.Content.OfString.Value
+		for _, call := range msg.OfAssistant.ToolCalls {
+			if call.OfFunction == nil {
+				continue
+			}
+			entry.ToolCalls = append(entry.ToolCalls, ToolCallEntry{
+				ID:        call.OfFunction.ID,
+				Name:      call.OfFunction.Function.Name,
+				Arguments: call.OfFunction.Function.Arguments,
+			})
+		}
+	case msg.OfTool != nil:
+		entry.Role = "tool"
+		entry.ToolCallID = msg.OfTool.ToolCallID
+		entry.Content = msg.OfTool.Content.OfString.Value
+	default:
+		return MemoryEntry{}, false
+	}
+
+	return entry, true
+}
+
+// sessionMessage converts a stored MemoryEntry back into an OpenAI message.
+// Returns false if the entry has an unsupported role.
+func sessionMessage(entry MemoryEntry) (openai.ChatCompletionMessageParamUnion, bool) {
+	switch entry.Role {
+	case "system":
+		return newSystemMsg(entry.Content), true
+	case "user":
+		return newUserMsg(entry.Content), true
+	case "assistant":
+		assistant := &openai.ChatCompletionAssistantMessageParam{Role: "assistant"}
+		if entry.Content != "" {
+			assistant.Content = openai.ChatCompletionAssistantMessageParamContentUnion{
+				OfString: openai.String(entry.Content),
+			}
+		}
+		for _, call := range entry.ToolCalls {
+			assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+				OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+					ID: call.ID,
+					Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+						Name:      call.Name,
+						Arguments: call.Arguments,
+					},
+				},
+			})
+		}
+		return openai.ChatCompletionMessageParamUnion{OfAssistant: assistant}, true
+	case "tool":
+		return openai.ChatCompletionMessageParamUnion{
+			OfTool: &openai.ChatCompletionToolMessageParam{
+				Role:       "tool",
+				ToolCallID: entry.ToolCallID,
+				Content: openai.ChatCompletionToolMessageParamContentUnion{
+					OfString: openai.String(entry.Content),
+				},
+			},
+		}, true
+	default:
+		return openai.ChatCompletionMessageParamUnion{}, false
+	}
+}
+
+// trimSession drops the oldest messages until the history fits within max.
+// The trimmed history always starts at a user message so tool results are never orphaned.
+// A leading summary (system message) is kept so compacted context isn't lost.
+func trimSession(history []openai.ChatCompletionMessageParamUnion, max int) []openai.ChatCompletionMessageParamUnion {
+	if max <= 0 || len(history) <= max {
+		return history
+	}
+
+	start := len(history) - max
+	for start < len(history) && history[start].OfUser == nil {
+		start++
+	}
+	if history[0].OfSystem != nil && start > 0 {
+		return append([]openai.ChatCompletionMessageParamUnion{history[0]}, history[start:]...)
+	}
+	return history[start:]
+}
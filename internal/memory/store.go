@@ -136,6 +136,7 @@ type searchRequest struct {
 	Vector      []float32 `json:"vector"`
 	Limit       int       `json:"limit"`
 	WithPayload bool      `json:"with_payload"`
+	Filter      any       `json:"filter,omitempty"`
 }
 
 // searchResponse parses Qdrant's search response
@@ -154,6 +155,7 @@ func (vs *VectorStore) Search(ctx context.Context, vector []float32, limit int,
 		Vector:      vector,
 		Limit:       limit,
 		WithPayload: true,
+		Filter:      filter,
 	}
 	body, _ := json.Marshal(reqBody)
 
@@ -235,6 +237,49 @@ func (vs *VectorStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteByFilter removes all memories matching a Qdrant filter.
+func (vs *VectorStore) DeleteByFilter(ctx context.Context, filter any) error {
+	body, _ := json.Marshal(map[string]any{"filter": filter})
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		vs.baseURL+"/collections/"+vs.collection+"/points/delete?wait=true", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := vs.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("delete failed: %s", string(b))
+	}
+	return nil
+}
+
+// Ping checks that Qdrant is reachable and the collection exists.
+func (vs *VectorStore) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", vs.baseURL+"/collections/"+vs.collection, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := vs.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("qdrant returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // Close implements the io.Closer interface.
 // Currently a no-op since HTTP client handles its own resources.
 func (vs *VectorStore) Close() error {
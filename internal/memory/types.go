@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/openai/openai-go/v3"
@@ -12,6 +14,13 @@ const (
 	MemoryDirName        = "memory"         // Subdirectory name for memory files
 	DayFileLayout        = "2006-01-02"     // Date format for daily files
 	ClockLayout          = "15:04:05"       // Time format for timestamps
+
+	SessionDirName            = "sessions"   // Subdirectory name for per-chat session files
+	DefaultSessionMaxMessages = 200          // Max messages kept in a session before trimming
+	NamespaceDirName          = "namespaces" // Subdirectory of the memory directory holding per-user namespaces
+	namespacePayloadKey       = "namespace"  // Vector payload field tagging a memory with its namespace
+	legacySessionPrefix       = "telegram:"  // Sessions of keys with this prefix used to be stored by bare chat ID
+	healthCheckTimeout        = 5 * time.Second
 )
 
 // MemoryType categorizes memories for filtering and retrieval.
@@ -43,6 +52,30 @@ type SearchResult struct {
 	Score  float64 // Similarity score (0-1, higher is better)
 }
 
+// KnownFactKind tells where a known fact is stored.
+type KnownFactKind string
+
+// Kinds of known facts
+const (
+	KnownProfile    KnownFactKind = "profile"    // Entry of the user profile
+	KnownPreference KnownFactKind = "preference" // User preference
+)
+
+// KnownFact is a structured fact the memory holds about the user, as listed by KnownFacts.
+type KnownFact struct {
+	Kind     KnownFactKind
+	ID       int64 // Row ID within its kind
+	Key      string
+	Value    string
+	Category string // Only set for preferences
+}
+
+// Health is the state of one memory backend.
+type Health struct {
+	Component string // e.g. "Qdrant"
+	Err       error  // nil if the backend is healthy
+}
+
 // Config holds configuration for the memory system.
 // Default values are provided via DefaultConfig().
 type Config struct {
@@ -56,7 +89,7 @@ type Config struct {
 // MemoryWriter is the interface for persisting conversation history.
 // Implementations can store to files, databases, etc.
 type MemoryWriter interface {
-	Write(messages []openai.ChatCompletionMessageParamUnion) error
+	Write(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) error
 }
 
 // FileMemoryWriter stores conversation history in daily JSONL files.
@@ -66,14 +99,35 @@ type FileMemoryWriter struct {
 	Dir     string           // Directory path for memory files
 	MaxSize int64            // Max file size before rotation (bytes)
 	Clock   func() time.Time // Clock for testing (defaults to time.Now)
+	mu      sync.Mutex       // Serializes writes from concurrent chats
 }
 
 // MemoryEntry represents a single message in conversation history.
 // Stored in JSONL format for easy appending and parsing.
+// Tool fields are only populated in session files.
 type MemoryEntry struct {
-	Timestamp string `json:"timestamp"` // Time in HH:MM:SS format
-	Role      string `json:"role"`      // "user" or "assistant"
-	Content   string `json:"content"`   // Message content
+	Timestamp  string          `json:"timestamp"`              // Time in HH:MM:SS format
+	Role       string          `json:"role"`                   // "system", "user", "assistant" or "tool"
+	Content    string          `json:"content"`                // Message content
+	ToolCalls  []ToolCallEntry `json:"tool_calls,omitempty"`   // Tool calls requested by the assistant
+	ToolCallID string          `json:"tool_call_id,omitempty"` // Tool call answered by a tool message
+}
+
+// ToolCallEntry represents a single tool call made by the assistant.
+type ToolCallEntry struct {
+	ID        string `json:"id"`        // Tool call identifier
+	Name      string `json:"name"`      // Tool name
+	Arguments string `json:"arguments"` // Raw JSON arguments
+}
+
+// SessionStore keeps the running message history of each conversation.
+// Sessions are cached in memory and persisted as JSONL files so they survive restarts.
+type SessionStore struct {
+	Dir         string // Directory path for session files
+	MaxMessages int    // Max messages kept per session
+
+	mu       sync.Mutex
+	sessions map[string][]openai.ChatCompletionMessageParamUnion // By conversation key, e.g. "telegram:123"
 }
 
 // DefaultConfig returns a Config with sensible defaults for local development.
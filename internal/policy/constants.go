@@ -0,0 +1,21 @@
+package policy
+
+import "github.com/Shreehari-Acharya/vayuu/config"
+
+const (
+	Auto    Decision = "auto"    // Runs right away
+	Confirm Decision = "confirm" // Waits for the user's approval
+	Deny    Decision = "deny"    // Is refused
+)
+
+// defaultRules are used when the config has no rules. They ask before commands that are hard to undo.
+var defaultRules = []config.PolicyRule{
+	{Pattern: `\brm\s+(-\S*\s+)*-\S*[rRf]`, Decision: "confirm", Reason: "recursive or forced delete"},
+	{Pattern: `\b(sudo|su|doas)\b`, Decision: "confirm", Reason: "runs as another user"},
+	{Pattern: `\b(mkfs(\.\w+)?|dd|fdisk|parted|wipefs|shred)\b`, Decision: "confirm", Reason: "can overwrite disks"},
+	{Pattern: `\b(shutdown|reboot|poweroff|halt|systemctl\s+(stop|disable|mask|poweroff|reboot))\b`, Decision: "confirm", Reason: "stops the system or its services"},
+	{Pattern: `\b(curl|wget)\b[^|]*\|\s*(sudo\s+)?(ba|z)?sh\b`, Decision: "confirm", Reason: "runs a downloaded script"},
+	{Pattern: `\bgit\s+(push\s+.*(--force|-f)\b|reset\s+--hard|clean\s+-\S*f)`, Decision: "confirm", Reason: "discards git history or changes"},
+	{Pattern: `\bchmod\s+(-\S+\s+)*(-R|777)|\bchown\s+-R`, Decision: "confirm", Reason: "changes permissions recursively"},
+	{Pattern: `:\(\)\s*\{.*\};\s*:`, Decision: "deny", Reason: "fork bomb"},
+}
@@ -0,0 +1,177 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/config"
+)
+
+// homePathPattern finds ~ paths in a shell command, e.g. "cat ~/.bashrc" or "cd ~".
+var homePathPattern = regexp.MustCompile(`(?:^|[\s'"=:;|&(])(~[\w.-]*(?:/[^\s'";|&)]*)?)(?:$|[\s'";|&)])`)
+
+// New compiles a policy from the config. Without rules, the built-in rules apply.
+func New(cfg config.PolicyConfig) (*Engine, error) {
+	e := &Engine{}
+	e.home, _ = os.UserHomeDir()
+
+	var err error
+	if e.homePaths, err = parseDecision(cfg.HomePaths, Confirm); err != nil {
+		return nil, fmt.Errorf("HomePaths: %w", err)
+	}
+	if e.outsideWrites, err = parseDecision(cfg.OutsideWrites, Confirm); err != nil {
+		return nil, fmt.Errorf("OutsideWrites: %w", err)
+	}
+	if e.fallback, err = parseDecision(cfg.Default, Auto); err != nil {
+		return nil, fmt.Errorf("Default: %w", err)
+	}
+
+	rules := cfg.Rules
+	if rules == nil {
+		rules = defaultRules
+	}
+	for i, r := range rules {
+		compiled := rule{tool: r.Tool, reason: r.Reason}
+		if compiled.decision, err = parseDecision(r.Decision, ""); err != nil || compiled.decision == "" {
+			return nil, fmt.Errorf("Rules[%d]: decision must be auto, confirm or deny, got %q", i, r.Decision)
+		}
+		if r.Pattern != "" {
+			if compiled.pattern, err = regexp.Compile(r.Pattern); err != nil {
+				return nil, fmt.Errorf("Rules[%d]: invalid pattern: %w", i, err)
+			}
+		}
+		e.rules = append(e.rules, compiled)
+	}
+
+	for _, dir := range cfg.WriteAllowlist {
+		if strings.HasPrefix(dir, "~/") && e.home != "" {
+			dir = filepath.Join(e.home, dir[2:])
+		}
+		e.writeAllowlist = append(e.writeAllowlist, filepath.Clean(dir))
+	}
+
+	return e, nil
+}
+
+// Evaluate classifies a call. The first matching rule, or the default, is combined with the path checks and the
+// strictest decision wins, so rules can't approve what the path checks hold back.
+func (e *Engine) Evaluate(call Call) Verdict {
+	verdict := Verdict{Decision: e.fallback}
+	for _, r := range e.rules {
+		if r.matches(call) {
+			verdict = Verdict{Decision: r.decision, Reason: r.reason}
+			if verdict.Reason == "" && r.pattern != nil {
+				verdict.Reason = "matches " + r.pattern.String()
+			}
+			break
+		}
+	}
+
+	if path, ok := e.outsideWrite(call); ok {
+		verdict = stricter(verdict, Verdict{Decision: e.outsideWrites, Reason: "writes outside the workspace: " + path})
+	}
+	if path, ok := e.homePath(call); ok {
+		verdict = stricter(verdict, Verdict{Decision: e.homePaths, Reason: "touches a path in the home directory: " + path})
+		verdict.Home = true
+	}
+
+	if verdict.Decision == Auto {
+		verdict.Reason = ""
+	}
+	return verdict
+}
+
+// matches reports whether a rule applies to a call.
+func (r rule) matches(call Call) bool {
+	if r.tool != "" && r.tool != call.Tool {
+		return false
+	}
+	if r.pattern == nil {
+		return true
+	}
+	for _, cmd := range call.Commands {
+		if r.pattern.MatchString(cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// outsideWrite returns the first path the call writes outside its workspace and the allowlist.
+func (e *Engine) outsideWrite(call Call) (string, bool) {
+	for _, path := range call.Writes {
+		if call.WorkDir != "" && within(path, call.WorkDir) {
+			continue
+		}
+		allowed := false
+		for _, dir := range e.writeAllowlist {
+			if within(path, dir) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// homePath returns the first path in the home directory, outside the workspace, that the call touches,
+// or the first ~ path in its commands.
+func (e *Engine) homePath(call Call) (string, bool) {
+	if e.home != "" {
+		for _, path := range append(append([]string{}, call.Reads...), call.Writes...) {
+			if within(path, e.home) && (call.WorkDir == "" || !within(path, call.WorkDir)) {
+				return path, true
+			}
+		}
+	}
+	for _, cmd := range call.Commands {
+		if m := homePathPattern.FindStringSubmatch(cmd); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// stricter returns the verdict with the stricter decision, preferring a when they are equal.
+func stricter(a, b Verdict) Verdict {
+	if rank(b.Decision) > rank(a.Decision) {
+		return b
+	}
+	return a
+}
+
+// rank orders decisions by strictness.
+func rank(d Decision) int {
+	switch d {
+	case Deny:
+		return 2
+	case Confirm:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// parseDecision parses a decision name, returning def for an empty one.
+func parseDecision(value string, def Decision) (Decision, error) {
+	switch d := Decision(strings.ToLower(strings.TrimSpace(value))); d {
+	case "":
+		return def, nil
+	case Auto, Confirm, Deny:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unknown decision %q", value)
+	}
+}
@@ -0,0 +1,40 @@
+package policy
+
+import "regexp"
+
+// Decision is what happens to a tool call.
+type Decision string
+
+// Engine classifies tool calls by the configured rules and path checks.
+type Engine struct {
+	rules          []rule
+	writeAllowlist []string // Directories besides the run's workspace that may be written without confirmation
+	homePaths      Decision // Applied to calls touching a ~/ path outside the workspace
+	outsideWrites  Decision // Applied to writes outside the workspace and the allowlist
+	fallback       Decision // Applied when no rule matches
+	home           string
+}
+
+// rule is a compiled PolicyRule.
+type rule struct {
+	tool     string         // Empty matches every tool
+	pattern  *regexp.Regexp // nil matches every call; otherwise one of the call's commands must match
+	decision Decision
+	reason   string
+}
+
+// Call is what the engine knows about a tool call.
+type Call struct {
+	Tool     string
+	Commands []string // Shell commands the call runs
+	Reads    []string // Absolute paths read
+	Writes   []string // Absolute paths created, modified or deleted
+	WorkDir  string   // Workspace of the run
+}
+
+// Verdict is the decision for a call and why it was made.
+type Verdict struct {
+	Decision Decision
+	Reason   string // Empty for calls approved automatically
+	Home     bool   // The call touches the home directory outside the workspace
+}
@@ -8,7 +8,11 @@ var SystemPrompt = `
 
 ## **STEP 2** Know your tools and skills
 - You can do a lot more than the provided tools. Just see"`+"`skills/readme.md`"+`" to understand available skills and their usage.
+- use `+"`list_dir`"+`, `+"`glob`"+` and `+"`grep`"+` to find files and code rather than `+"`ls`"+`, `+"`find`"+` or `+"`grep`"+` commands.
+- use `+"`apply_patch`"+` for changes to several places or files at once, and `+"`read_file`"+` with `+"`offset`"+` and `+"`limit`"+` for large files.
 - use `+"`execute_command`"+`" for only simple system commands. read "`+"`skills/readme.md`"+` to find ways for complex tasks.
+- use `+"`start_job`"+` for commands that take longer than 30 seconds, such as builds or downloads, and follow them with `+"`job_status`"+`.
+- use `+"`shell_open`"+` and `+"`shell_send`"+` for interactive programs, such as REPLs or commands that ask questions, and `+"`shell_close`"+` when done.
 
 ## **STEP 3** Keeping your knowledge up-to-date and relevant
 - Update "`+"`SOUL.md`"+`" if user gives new/updated information about you, your behaviour, restrictions or anything related to you.
@@ -0,0 +1,15 @@
+package sandbox
+
+const (
+	None   Backend = "none"   // Commands run directly as the Vayuu user
+	Bwrap  Backend = "bwrap"  // Commands run under bubblewrap
+	Native Backend = "native" // Vayuu sets up the namespaces itself by re-executing its binary
+)
+
+const (
+	// InitCommand is the hidden subcommand the native sandbox re-executes the Vayuu binary with.
+	InitCommand = "__sandbox-init"
+
+	// initFailed is the exit status of a native sandbox that couldn't be set up, like env's for a command that can't run.
+	initFailed = 125
+)
@@ -0,0 +1,174 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// nativeSupported reports whether the native sandbox can run on this system.
+func nativeSupported() error {
+	return nil
+}
+
+// nativeCommand re-executes the Vayuu binary as the init process of new user, mount, PID and IPC namespaces,
+// and a network namespace without network access if asked. RunInit sets up the mounts there and runs the script.
+func (s *Sandbox) nativeCommand(ctx context.Context, dir, script string) *exec.Cmd {
+	spec, _ := json.Marshal(initSpec{Dir: dir, Hide: s.hide, UID: os.Getuid(), GID: os.Getgid(), Loopback: s.noNetwork})
+
+	cmd := exec.CommandContext(ctx, s.self, InitCommand, string(spec), script)
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if s.noNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  uintptr(flags),
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+	return cmd
+}
+
+// RunInit is the init process of a native sandbox, run by the Vayuu binary when started with InitCommand.
+// It mounts the sandbox's view of the filesystem and runs the script as the original user, returning its exit status.
+// The sandbox ends with it: the kernel kills whatever the script left running.
+func RunInit(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "sandbox: expected a spec and a script")
+		return initFailed
+	}
+
+	var spec initSpec
+	if err := json.Unmarshal([]byte(args[0]), &spec); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: invalid spec: %v\n", err)
+		return initFailed
+	}
+	if err := spec.mount(); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return initFailed
+	}
+	if spec.Loopback {
+		if err := loopbackUp(); err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+			return initFailed
+		}
+	}
+
+	// A nested user namespace maps the original user back, so the script doesn't run as root and can't undo the mounts.
+	cmd := exec.Command("bash", "-c", args[1])
+	cmd.Dir = spec.Dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: spec.UID, HostID: 0, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: spec.GID, HostID: 0, Size: 1}},
+	}
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	default:
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return initFailed
+	}
+}
+
+// mount turns the inherited mounts into the sandbox's view: everything read-only, the hidden paths covered,
+// a private /tmp and /proc, and the workspace writable.
+func (spec initSpec) mount() error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+
+	// Detach a writable copy of the workspace before everything becomes read-only and it may get hidden.
+	workspace, err := unix.OpenTree(unix.AT_FDCWD, spec.Dir, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC|unix.AT_RECURSIVE)
+	if err != nil {
+		return fmt.Errorf("clone workspace mount: %w", err)
+	}
+	defer unix.Close(workspace)
+
+	readOnly := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}
+	if err := unix.MountSetattr(unix.AT_FDCWD, "/", unix.AT_RECURSIVE, readOnly); err != nil {
+		return fmt.Errorf("make the system read-only (needs Linux 5.12 or later): %w", err)
+	}
+
+	if err := unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+
+	// Hidden paths under /tmp are already gone with it.
+	var hiddenDirs []string
+	for _, path := range spec.Hide {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			continue
+		case info.IsDir():
+			if err := unix.Mount("tmpfs", path, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+				return fmt.Errorf("hide %s: %w", path, err)
+			}
+			hiddenDirs = append(hiddenDirs, path)
+		default:
+			if err := unix.Mount(os.DevNull, path, "", unix.MS_BIND, ""); err != nil {
+				return fmt.Errorf("hide %s: %w", path, err)
+			}
+		}
+	}
+
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	// The workspace may be inside a hidden directory, whose tmpfs is still writable for its mount point.
+	if err := os.MkdirAll(spec.Dir, 0755); err != nil {
+		return fmt.Errorf("create workspace mount point: %w", err)
+	}
+	if err := unix.MoveMount(workspace, "", unix.AT_FDCWD, spec.Dir, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		return fmt.Errorf("mount workspace: %w", err)
+	}
+
+	for _, path := range hiddenDirs {
+		if err := unix.MountSetattr(unix.AT_FDCWD, path, 0, readOnly); err != nil {
+			return fmt.Errorf("make %s read-only: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// loopbackUp brings up the loopback interface of a new network namespace, which starts down.
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("bring up loopback: %w", err)
+	}
+	defer unix.Close(fd)
+
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return fmt.Errorf("bring up loopback: %w", err)
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("bring up loopback: %w", err)
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	if err := unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("bring up loopback: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// nativeSupported reports whether the native sandbox can run on this system.
+func nativeSupported() error {
+	return fmt.Errorf("native sandbox: only supported on Linux")
+}
+
+// nativeCommand is never used, since New refuses the native backend here.
+func (s *Sandbox) nativeCommand(ctx context.Context, dir, script string) *exec.Cmd {
+	return exec.CommandContext(ctx, "bash", "-c", script)
+}
+
+// RunInit only exists on Linux.
+func RunInit(args []string) int {
+	fmt.Fprintln(os.Stderr, "sandbox: only supported on Linux")
+	return initFailed
+}
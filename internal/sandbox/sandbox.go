@@ -0,0 +1,177 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/config"
+)
+
+// New prepares the sandbox described by the config. Besides the configured paths, commands never see the home
+// directory of the user running Vayuu or the Vayuu config, which holds its tokens.
+func New(cfg config.SandboxConfig) (*Sandbox, error) {
+	s := &Sandbox{
+		backend:   Backend(strings.ToLower(cfg.Backend)),
+		noNetwork: cfg.NoNetwork,
+		limits:    ulimitLine(cfg),
+	}
+	if s.backend == "" {
+		s.backend = None
+	}
+
+	switch s.backend {
+	case None:
+		if cfg.NoNetwork {
+			return nil, fmt.Errorf("SANDBOX_NO_NETWORK needs the bwrap or native sandbox")
+		}
+		return s, nil
+	case Bwrap:
+		path, err := exec.LookPath("bwrap")
+		if err != nil {
+			return nil, fmt.Errorf("bwrap sandbox: bubblewrap is not installed: %w", err)
+		}
+		s.bwrap = path
+	case Native:
+		if err := nativeSupported(); err != nil {
+			return nil, err
+		}
+		self, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("native sandbox: locate the vayuu binary: %w", err)
+		}
+		s.self = self
+	default:
+		return nil, fmt.Errorf("unknown sandbox backend %q", cfg.Backend)
+	}
+
+	var hide []string
+	if home, err := os.UserHomeDir(); err == nil {
+		hide = append(hide, home)
+	}
+	hide = append(hide, filepath.Dir(config.UsersFilePath()))
+	s.hide = outermost(append(hide, cfg.Hide...))
+	return s, nil
+}
+
+// Command prepares a bash script to run in dir, which is the only directory it may write besides a private /tmp.
+// A nil Sandbox runs the script directly, without limits.
+func (s *Sandbox) Command(ctx context.Context, dir, script string) *exec.Cmd {
+	var cmd *exec.Cmd
+	switch s.Backend() {
+	case Bwrap:
+		cmd = exec.CommandContext(ctx, s.bwrap, s.bwrapArgs(dir, s.limits+script)...)
+	case Native:
+		cmd = s.nativeCommand(ctx, dir, s.limits+script)
+	default:
+		if s != nil {
+			script = s.limits + script
+		}
+		cmd = exec.CommandContext(ctx, "bash", "-c", script)
+	}
+	cmd.Dir = dir
+	return cmd
+}
+
+// Check runs a trivial command in the sandbox, so a backend the system doesn't support fails at startup.
+func (s *Sandbox) Check(ctx context.Context, dir string) error {
+	if s.Backend() == None {
+		return nil
+	}
+
+	output, err := s.Command(ctx, dir, "true").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s sandbox doesn't work here: %v: %s", s.backend, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Backend returns the backend commands run with.
+func (s *Sandbox) Backend() Backend {
+	if s == nil {
+		return None
+	}
+	return s.backend
+}
+
+// bwrapArgs builds the bubblewrap command line: a read-only view of the system with its own /dev, /proc and /tmp,
+// the hidden paths covered and dir bound writable.
+func (s *Sandbox) bwrapArgs(dir, script string) []string {
+	args := []string{"--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp"}
+
+	var hiddenDirs []string
+	for _, path := range s.hide {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			continue
+		case info.IsDir():
+			args = append(args, "--tmpfs", path)
+			hiddenDirs = append(hiddenDirs, path)
+		default:
+			args = append(args, "--ro-bind", os.DevNull, path)
+		}
+	}
+
+	args = append(args, "--bind", dir, dir)
+	for _, path := range hiddenDirs {
+		args = append(args, "--remount-ro", path)
+	}
+
+	args = append(args, "--unshare-user", "--unshare-pid", "--unshare-ipc", "--unshare-uts", "--die-with-parent", "--chdir", dir)
+	if s.noNetwork {
+		args = append(args, "--unshare-net")
+	}
+	return append(args, "--", "bash", "-c", script)
+}
+
+// ulimitLine returns the bash line that applies the configured resource limits, or "" when none is set.
+// A limit that can't be applied stops the command rather than letting it run without.
+func ulimitLine(cfg config.SandboxConfig) string {
+	var flags []string
+	if cfg.CPUSeconds > 0 {
+		flags = append(flags, fmt.Sprintf("-t %d", cfg.CPUSeconds))
+	}
+	if cfg.MemoryMB > 0 {
+		flags = append(flags, fmt.Sprintf("-v %d", cfg.MemoryMB*1024))
+	}
+	if cfg.MaxProcesses > 0 {
+		flags = append(flags, fmt.Sprintf("-u %d", cfg.MaxProcesses))
+	}
+	if cfg.MaxFileSizeMB > 0 {
+		flags = append(flags, fmt.Sprintf("-f %d", cfg.MaxFileSizeMB*1024))
+	}
+	if len(flags) == 0 {
+		return ""
+	}
+	return "ulimit " + strings.Join(flags, " ") + " || exit 1\n"
+}
+
+// outermost cleans the paths and drops those inside another one, which are hidden along with it.
+func outermost(paths []string) []string {
+	var kept []string
+	for i, path := range paths {
+		path = filepath.Clean(path)
+		covered := false
+		for j, other := range paths {
+			other = filepath.Clean(other)
+			if i != j && within(path, other) && (path != other || j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,23 @@
+package sandbox
+
+// Backend isolates a command from the rest of the system.
+type Backend string
+
+// Sandbox runs shell commands with the configured backend and resource limits.
+type Sandbox struct {
+	backend   Backend
+	noNetwork bool
+	hide      []string // Paths hidden from commands: the home directory, the Vayuu config and configured ones
+	limits    string   // ulimit line run before every command; empty without limits
+	bwrap     string   // Path of the bubblewrap binary
+	self      string   // Path of the Vayuu binary, re-executed to set up the native sandbox
+}
+
+// initSpec tells the native sandbox's init process what to set up. It is passed as JSON on its command line.
+type initSpec struct {
+	Dir      string   // Workspace of the command; the only writable path besides /tmp
+	Hide     []string // Paths covered by an empty read-only tmpfs, or /dev/null for files
+	UID      int      // User the command runs as, mapped from the sandbox's root
+	GID      int
+	Loopback bool // The sandbox has its own network namespace, whose loopback must be brought up
+}
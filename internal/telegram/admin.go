@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
+	"github.com/go-telegram/bot/models"
+)
+
+// authorize returns the registered user behind a Telegram account. When no admin exists yet,
+// the account matching the configured username claims the admin role.
+func (tb *Bot) authorize(from *models.User) (users.User, bool) {
+	if from == nil {
+		return users.User{}, false
+	}
+
+	if user, ok := tb.users.Get(from.ID); ok {
+		return user, true
+	}
+
+	if tb.cfg.AllowedUsername == "" || from.Username != tb.cfg.AllowedUsername || tb.users.HasAdmin() {
+		return users.User{}, false
+	}
+
+	user, err := tb.users.Bootstrap(from.ID, from.Username)
+	if err != nil {
+		slog.Error("failed to register admin", "user_id", from.ID, "error", err)
+		return users.User{}, false
+	}
+	go tb.registerAdminCommands(context.Background(), user.ID)
+	return user, true
+}
+
+// handleJoin registers the sender with an invite code. It is the only command open to unregistered users.
+func (tb *Bot) handleJoin(ctx context.Context, chatID int64, from *models.User, code string) {
+	if code == "" {
+		_ = tb.sendMessage(ctx, chatID, "Usage: /join <invite code>")
+		return
+	}
+
+	user, err := tb.users.Redeem(code, from.ID, from.Username)
+	if err != nil {
+		slog.Warn("failed to redeem invite", "user_id", from.ID, "username", from.Username, "error", err)
+		_ = tb.sendMessage(ctx, chatID, fmt.Sprintf("Couldn't join: %v", err))
+		return
+	}
+
+	if user.Role == users.RoleAdmin {
+		tb.registerAdminCommands(ctx, user.ID)
+	}
+	if err := tb.sendMessage(ctx, chatID, fmt.Sprintf("Welcome! You joined as %s.", user.Role)); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// handleInvite creates an invite code for a role.
+func (tb *Bot) handleInvite(ctx context.Context, chatID int64, admin users.User, arg string) {
+	if arg == "" {
+		arg = string(users.RoleOperator)
+	}
+
+	role, err := users.ParseRole(arg)
+	if err != nil {
+		_ = tb.sendMessage(ctx, chatID, err.Error())
+		return
+	}
+
+	inv, err := tb.users.CreateInvite(role, admin.ID)
+	if err != nil {
+		slog.Error("failed to create invite", "error", err)
+		_ = tb.sendMessage(ctx, chatID, "Sorry, I couldn't create an invite.")
+		return
+	}
+
+	text := fmt.Sprintf("Invite for a new %s, valid until %s:\n\n%s %s\n\nAsk them to send this to me.",
+		inv.Role, inv.ExpiresAt.Format("2006-01-02 15:04 MST"), joinCommand, inv.Code)
+	if err := tb.sendMessage(ctx, chatID, text); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// handleUsers lists the registered users.
+func (tb *Bot) handleUsers(ctx context.Context, chatID int64) {
+	var sb strings.Builder
+	sb.WriteString("Users:\n")
+	for _, user := range tb.users.List() {
+		name := user.Username
+		if name == "" {
+			name = "(no username)"
+		} else {
+			name = "@" + name
+		}
+		fmt.Fprintf(&sb, "\n%d %s — %s", user.ID, name, user.Role)
+	}
+
+	if err := tb.sendMessage(ctx, chatID, sb.String()); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// handleRevoke removes a user by Telegram user ID.
+func (tb *Bot) handleRevoke(ctx context.Context, chatID int64, arg string) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		_ = tb.sendMessage(ctx, chatID, "Usage: /revoke <user id> (see /users)")
+		return
+	}
+
+	user, err := tb.users.Revoke(id)
+	if err != nil {
+		_ = tb.sendMessage(ctx, chatID, fmt.Sprintf("Couldn't revoke: %v", err))
+		return
+	}
+	if user.Role == users.RoleAdmin {
+		tb.unregisterAdminCommands(ctx, user.ID)
+	}
+
+	if err := tb.sendMessage(ctx, chatID, fmt.Sprintf("Revoked %d (%s).", user.ID, user.Role)); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// splitCommand splits a message into its command, without any @botname suffix, and the remaining argument.
+// The command is empty if the text is not a command.
+func splitCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", ""
+	}
+
+	command, arg, _ := strings.Cut(text, " ")
+	command, _, _ = strings.Cut(command, "@")
+	return command, strings.TrimSpace(arg)
+}
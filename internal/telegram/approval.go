@@ -0,0 +1,181 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// approver returns the function the agent calls to ask for approval of a tool call during a user's run.
+// Requests are threaded under replyTo, the message that started the run.
+func (tb *Bot) approver(chatID int64, requester users.User, replyTo int) agent.ApproveFunc {
+	return func(ctx context.Context, req agent.ApprovalRequest) (bool, error) {
+		return tb.requestApproval(ctx, chatID, requester, replyTo, req)
+	}
+}
+
+// requestApproval shows a tool call with Approve and Deny buttons and waits for one of them, or for ctx to end.
+// The request is edited to show the outcome and lose its buttons.
+func (tb *Bot) requestApproval(ctx context.Context, chatID int64, requester users.User, replyTo int, req agent.ApprovalRequest) (bool, error) {
+	id, pending := tb.addApproval(chatID, requester.ID, req.AdminOnly)
+	defer tb.removeApproval(id)
+
+	text := approvalText(req)
+	sent, err := tb.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		Text:            text,
+		ParseMode:       models.ParseModeHTML,
+		ReplyMarkup:     approvalKeyboard(id),
+		ReplyParameters: replyParameters(replyTo),
+	})
+	if err != nil {
+		return false, fmt.Errorf("send approval request: %w", err)
+	}
+
+	var approved bool
+	var outcome string
+	select {
+	case answer := <-pending.answer:
+		approved = answer.approved
+		if approved {
+			outcome = "✅ Approved by " + answer.by
+		} else {
+			outcome = "❌ Denied by " + answer.by
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			outcome = "⌛ Not answered in time, so it was not run"
+		} else {
+			outcome = "⏹ Cancelled"
+		}
+	}
+
+	// The run's context may be over, but the request should still show how it ended.
+	if _, editErr := tb.bot.EditMessageText(context.WithoutCancel(ctx), &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: sent.ID,
+		Text:      text + "\n\n" + html.EscapeString(outcome),
+		ParseMode: models.ParseModeHTML,
+	}); editErr != nil && !isNotModified(editErr) {
+		slog.Debug("failed to update approval request", "error", editErr)
+	}
+	return approved, err
+}
+
+// handleApprovalButton passes a press of Approve or Deny to the waiting tool call.
+// Only the user whose run made the call, or an admin, may answer; calls marked admin-only need an admin.
+func (tb *Bot) handleApprovalButton(ctx context.Context, b *bot.Bot, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	answer := &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID}
+	defer func() {
+		if _, err := b.AnswerCallbackQuery(ctx, answer); err != nil {
+			slog.Debug("failed to answer callback query", "error", err)
+		}
+	}()
+
+	id, approved, ok := parseApprovalCallback(query.Data)
+	if !ok {
+		answer.Text = "Unknown button."
+		return
+	}
+
+	tb.approvalMu.Lock()
+	pending, found := tb.approvals[id]
+	tb.approvalMu.Unlock()
+	if !found {
+		answer.Text = "This request is no longer waiting."
+		return
+	}
+
+	user, registered := tb.users.Get(query.From.ID)
+	if !registered || user.Role != users.RoleAdmin && (pending.adminOnly || user.ID != pending.requesterID) {
+		slog.Warn("rejected approval from unauthorized user", "user_id", query.From.ID, "username", query.From.Username)
+		answer.Text = "Not allowed."
+		return
+	}
+
+	select {
+	case pending.answer <- approvalAnswer{approved: approved, by: senderName(&query.From)}:
+		slog.Info("approval answered", "approval_id", id, "approved", approved, "user_id", user.ID)
+		if approved {
+			answer.Text = "Approved."
+		} else {
+			answer.Text = "Denied."
+		}
+	default:
+		answer.Text = "Already answered."
+	}
+}
+
+// addApproval registers a pending approval and returns its ID.
+func (tb *Bot) addApproval(chatID, requesterID int64, adminOnly bool) (string, *pendingApproval) {
+	tb.approvalMu.Lock()
+	defer tb.approvalMu.Unlock()
+
+	tb.approvalSeq++
+	id := strconv.Itoa(tb.approvalSeq)
+	pending := &pendingApproval{chatID: chatID, requesterID: requesterID, adminOnly: adminOnly, answer: make(chan approvalAnswer, 1)}
+	tb.approvals[id] = pending
+	return id, pending
+}
+
+// removeApproval forgets a pending approval once it is answered or given up on.
+func (tb *Bot) removeApproval(id string) {
+	tb.approvalMu.Lock()
+	defer tb.approvalMu.Unlock()
+	delete(tb.approvals, id)
+}
+
+// approvalText renders an approval request as HTML, showing exactly what the call would do.
+func approvalText(req agent.ApprovalRequest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⚠️ <b>Approve %s?</b>\n", html.EscapeString(req.Tool))
+	if req.Reason != "" {
+		fmt.Fprintf(&sb, "Reason: %s\n", html.EscapeString(req.Reason))
+	}
+	if req.AdminOnly {
+		sb.WriteString("Needs an admin's approval.\n")
+	}
+	fmt.Fprintf(&sb, "<pre>%s</pre>", html.EscapeString(truncateRunes(req.Summary, maxApprovalSummary)))
+	return sb.String()
+}
+
+// approvalKeyboard is the inline keyboard with the Approve and Deny buttons of an approval request.
+func approvalKeyboard(id string) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{{
+			{Text: approveButtonText, CallbackData: approvalCallbackPrefix + id + ":y"},
+			{Text: denyButtonText, CallbackData: approvalCallbackPrefix + id + ":n"},
+		}},
+	}
+}
+
+// parseApprovalCallback splits the data of an approval button into the approval ID and the answer.
+func parseApprovalCallback(data string) (string, bool, bool) {
+	id, answer, ok := strings.Cut(strings.TrimPrefix(data, approvalCallbackPrefix), ":")
+	if !ok || id == "" {
+		return "", false, false
+	}
+	switch answer {
+	case "y":
+		return id, true, true
+	case "n":
+		return id, false, true
+	default:
+		return "", false, false
+	}
+}
@@ -4,24 +4,53 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/Shreehari-Acharya/vayuu/config"
 	"github.com/Shreehari-Acharya/vayuu/internal/agent"
 	"github.com/Shreehari-Acharya/vayuu/internal/tools"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
 	"github.com/go-telegram/bot"
 )
 
-// Bot encapsulates the Telegram bot functionality, 
+// Bot encapsulates the Telegram bot functionality,
 // integrating with the agent and tool environment to handle incoming messages and execute tools as needed.
-func NewBot(cfg *config.Config, agentInstance *agent.Agent, toolEnv *tools.ToolEnv) (*Bot, error) {
+func NewBot(cfg *config.Config, agentInstance *agent.Agent, toolEnv *tools.ToolEnv, registry *users.Registry) (*Bot, error) {
+	maxChats := cfg.MaxConcurrentChats
+	if maxChats == 0 {
+		maxChats = defaultMaxConcurrentChats
+	}
+
 	tb := &Bot{
 		agent:   agentInstance,
 		cfg:     cfg,
 		toolEnv: toolEnv,
+		users:   registry,
+		runs:    make(map[int64]activeRun),
+		queues:  make(map[int64]*chatQueue),
+		slots:   make(chan struct{}, maxChats),
+		started: time.Now(),
+
+		approvals: make(map[string]*pendingApproval),
 	}
+	tb.commands = tb.newCommands()
+
+	stt, err := newTranscriber(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tb.transcriber = stt
 
 	opts := []bot.Option{
 		bot.WithDefaultHandler(tb.handleMessage),
+		bot.WithCallbackQueryDataHandler(stopCallbackData, bot.MatchTypeExact, tb.handleStopButton),
+		bot.WithCallbackQueryDataHandler(memoryCallbackPrefix, bot.MatchTypePrefix, tb.handleMemoryButton),
+		bot.WithCallbackQueryDataHandler(approvalCallbackPrefix, bot.MatchTypePrefix, tb.handleApprovalButton),
+	}
+
+	if cfg.TelegramAPIURL != "" {
+		opts = append(opts, bot.WithServerURL(strings.TrimSuffix(cfg.TelegramAPIURL, "/")))
 	}
 
 	b, err := bot.New(cfg.TelegramToken, opts...)
@@ -30,20 +59,33 @@ func NewBot(cfg *config.Config, agentInstance *agent.Agent, toolEnv *tools.ToolE
 	}
 	tb.bot = b
 
-	toolEnv.SetFileSender(tb.SendContent)
-	slog.Info("telegram bot initialized")
+	me, err := b.GetMe(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("get bot identity: %w", err)
+	}
+	tb.userID = me.ID
+	tb.username = me.Username
+
+	slog.Info("telegram bot initialized", "username", tb.username)
 
 	return tb, nil
 }
 
-// Start begins the bot's message processing loop, allowing it to receive and respond to messages from users.
-func (tb *Bot) Start(ctx context.Context) {
+// Start publishes the command menu and receives messages until ctx is done, through the webhook when one is
+// configured and by long polling otherwise. It returns an error if the webhook can't be served or registered.
+func (tb *Bot) Start(ctx context.Context) error {
+	tb.registerCommands(ctx)
+
+	if tb.cfg.WebhookURL != "" {
+		return tb.runWebhook(ctx)
+	}
+
+	// getUpdates fails while a webhook is set, e.g. one left behind by a webhook run that didn't shut down cleanly.
+	if _, err := tb.bot.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		slog.Warn("failed to remove webhook before polling", "error", err)
+	}
+
 	slog.Info("telegram bot started, listening for messages")
 	tb.bot.Start(ctx)
-}
-
-// setCurrentChatID updates the current chat ID in both the Bot struct and the tool environment, ensuring that tools have access to the correct chat context when sending messages or files.
-func (tb *Bot) setCurrentChatID(chatID int64) {
-	tb.currentChatID = chatID
-	tb.toolEnv.SetCurrentChatID(chatID)
+	return nil
 }
@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+	"github.com/Shreehari-Acharya/vayuu/internal/channel"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
+)
+
+var _ channel.Channel = (*Bot)(nil)
+
+// Name identifies the Telegram channel in config and in conversation keys.
+func (tb *Bot) Name() string {
+	return channelName
+}
+
+// SendText sends Markdown text to a chat, rendered into Telegram HTML.
+func (tb *Bot) SendText(ctx context.Context, chat, text string) error {
+	chatID, err := parseChat(chat)
+	if err != nil {
+		return err
+	}
+	return tb.sendMessage(ctx, chatID, text)
+}
+
+// Wake runs the agent on text in a chat on behalf of a registered user, after the messages already queued there.
+func (tb *Bot) Wake(ctx context.Context, chat, user, text string) error {
+	chatID, err := parseChat(chat)
+	if err != nil {
+		return err
+	}
+	userID, err := strconv.ParseInt(user, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid telegram user %q", user)
+	}
+	u, ok := tb.users.Get(userID)
+	if !ok {
+		return fmt.Errorf("telegram user %d is not registered", userID)
+	}
+
+	tb.enqueue(ctx, chatID, func(ctx context.Context) {
+		runCtx, endRun := tb.beginRun(ctx, chatID, u.ID)
+		defer endRun()
+
+		slog.Info("agent woken", "user_id", u.ID, "chat_id", chatID)
+		tb.respond(ctx, runCtx, u, chatID, 0, agent.Input{Text: text})
+	})
+	return nil
+}
+
+// origin identifies a Telegram chat to the agent.
+func origin(chatID int64) agent.Origin {
+	return agent.Origin{Channel: channelName, Chat: strconv.FormatInt(chatID, 10)}
+}
+
+// userOrigin identifies a Telegram chat and the user a run is for to the agent.
+func userOrigin(chatID int64, user users.User) agent.Origin {
+	o := origin(chatID)
+	o.User = strconv.FormatInt(user.ID, 10)
+	return o
+}
+
+// parseChat parses the chat ID of a Telegram chat.
+func parseChat(chat string) (int64, error) {
+	chatID, err := strconv.ParseInt(chat, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid telegram chat %q", chat)
+	}
+	return chatID, nil
+}
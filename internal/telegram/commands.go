@@ -0,0 +1,210 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/memory"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// newCommands returns the commands handled by the bot, in the order they appear in Telegram's command menu.
+func (tb *Bot) newCommands() []command {
+	return []command{
+		{name: resetCommand, description: "Clear the conversation", queued: true, handle: func(ctx context.Context, req commandRequest) {
+			tb.handleReset(ctx, req.chatID)
+		}},
+		{name: stopCommand, description: "Stop the current run", handle: func(ctx context.Context, req commandRequest) {
+			tb.handleStop(ctx, req.chatID, req.user)
+		}},
+		{name: statusCommand, description: "Show model, uptime, memory health and queued jobs", handle: tb.handleStatus},
+		{name: toolsCommand, description: "List the tools available to you", handle: tb.handleTools},
+		{name: memoryCommand, description: "Show and delete what I know about you", handle: tb.handleMemory},
+		{name: modelCommand, description: "Show or switch the model", access: accessAdmin, handle: tb.handleModel},
+		{name: inviteCommand, description: "Create an invite code", access: accessAdmin, handle: tb.handleInviteCommand},
+		{name: usersCommand, description: "List registered users", access: accessAdmin, handle: func(ctx context.Context, req commandRequest) {
+			tb.handleUsers(ctx, req.chatID)
+		}},
+		{name: revokeCommand, description: "Remove a user", access: accessAdmin, handle: func(ctx context.Context, req commandRequest) {
+			tb.handleRevoke(ctx, req.chatID, req.arg)
+		}},
+		{name: joinCommand, description: "Join with an invite code", access: accessAnyone, handle: tb.handleJoinCommand},
+	}
+}
+
+// command looks up a command by name.
+func (tb *Bot) command(name string) (command, bool) {
+	for _, cmd := range tb.commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// runCommand checks the sender may use a command and runs it, right away or on the chat's queue.
+func (tb *Bot) runCommand(ctx context.Context, cmd command, req commandRequest) {
+	switch {
+	case cmd.access != accessAnyone && !req.registered:
+		slog.Warn("rejected command from unauthorized user", "command", cmd.name, "user_id", req.msg.From.ID, "username", req.msg.From.Username, "chat_id", req.chatID)
+		return
+	case cmd.access == accessAdmin && req.user.Role != users.RoleAdmin:
+		_ = tb.replyMessage(ctx, req.chatID, threadID(req.msg), fmt.Sprintf("Only admins can use %s.", cmd.name))
+		return
+	}
+
+	slog.Info("running command", "command", cmd.name, "user_id", req.msg.From.ID, "chat_id", req.chatID)
+	if !cmd.queued {
+		cmd.handle(ctx, req)
+		return
+	}
+	tb.queueJob(ctx, req.msg, func(ctx context.Context) { cmd.handle(ctx, req) })
+}
+
+// registerCommands publishes the command menu through setMyCommands: admin commands are only listed in the private chats of admins.
+func (tb *Bot) registerCommands(ctx context.Context) {
+	var public []models.BotCommand
+	for _, cmd := range tb.commands {
+		if cmd.access != accessAdmin {
+			public = append(public, botCommand(cmd))
+		}
+	}
+	if _, err := tb.bot.SetMyCommands(ctx, &bot.SetMyCommandsParams{Commands: public, Scope: &models.BotCommandScopeDefault{}}); err != nil {
+		slog.Warn("failed to register commands", "error", err)
+	}
+
+	for _, user := range tb.users.List() {
+		if user.Role == users.RoleAdmin {
+			tb.registerAdminCommands(ctx, user.ID)
+		}
+	}
+}
+
+// registerAdminCommands publishes the full command menu in the private chat of an admin.
+func (tb *Bot) registerAdminCommands(ctx context.Context, userID int64) {
+	all := make([]models.BotCommand, 0, len(tb.commands))
+	for _, cmd := range tb.commands {
+		all = append(all, botCommand(cmd))
+	}
+	if _, err := tb.bot.SetMyCommands(ctx, &bot.SetMyCommandsParams{Commands: all, Scope: &models.BotCommandScopeChat{ChatID: userID}}); err != nil {
+		slog.Warn("failed to register admin commands", "user_id", userID, "error", err)
+	}
+}
+
+// unregisterAdminCommands removes the admin command menu from a user's private chat.
+func (tb *Bot) unregisterAdminCommands(ctx context.Context, userID int64) {
+	if _, err := tb.bot.DeleteMyCommands(ctx, &bot.DeleteMyCommandsParams{Scope: &models.BotCommandScopeChat{ChatID: userID}}); err != nil {
+		slog.Warn("failed to remove admin commands", "user_id", userID, "error", err)
+	}
+}
+
+// botCommand converts a command to its setMyCommands entry, which is named without the slash.
+func botCommand(cmd command) models.BotCommand {
+	return models.BotCommand{Command: strings.TrimPrefix(cmd.name, "/"), Description: cmd.description}
+}
+
+// handleJoinCommand registers an unregistered sender with an invite code, in private chats only.
+func (tb *Bot) handleJoinCommand(ctx context.Context, req commandRequest) {
+	switch {
+	case req.registered:
+		_ = tb.sendMessage(ctx, req.chatID, "You are already registered.")
+	case isGroupChat(req.msg.Chat):
+		_ = tb.replyMessage(ctx, req.chatID, req.msg.ID, "Send /join to me in a private chat so your invite code stays private.")
+	default:
+		tb.handleJoin(ctx, req.chatID, req.msg.From, req.arg)
+	}
+}
+
+// handleInviteCommand creates an invite code, in private chats only so the code isn't seen by the group.
+func (tb *Bot) handleInviteCommand(ctx context.Context, req commandRequest) {
+	if isGroupChat(req.msg.Chat) {
+		_ = tb.replyMessage(ctx, req.chatID, req.msg.ID, "Send /invite to me in a private chat so the invite code stays private.")
+		return
+	}
+	tb.handleInvite(ctx, req.chatID, req.user, req.arg)
+}
+
+// handleStatus reports the models, uptime, health of the memory backends and the state of the queues.
+func (tb *Bot) handleStatus(ctx context.Context, req commandRequest) {
+	names := tb.agent.Models()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**Model:** `%s`\n", names[0])
+	if len(names) > 1 {
+		fmt.Fprintf(&sb, "**Fallbacks:** `%s`\n", strings.Join(names[1:], "`, `"))
+	}
+	fmt.Fprintf(&sb, "**Uptime:** %s\n", time.Since(tb.started).Round(time.Second))
+
+	sb.WriteString("\n**Memory:**\n")
+	if mgr := tb.agent.Memory(); mgr == nil {
+		sb.WriteString("❌ not available\n")
+	} else {
+		for _, h := range mgr.Health(memory.WithNamespace(ctx, req.user.Namespace)) {
+			if h.Err != nil {
+				fmt.Fprintf(&sb, "❌ %s: %v\n", h.Component, h.Err)
+			} else {
+				fmt.Fprintf(&sb, "✅ %s\n", h.Component)
+			}
+		}
+	}
+
+	busy, waiting := tb.queueStats()
+	fmt.Fprintf(&sb, "\n**Jobs:** %d running, %d queued", busy, waiting)
+
+	if err := tb.sendMessage(ctx, req.chatID, sb.String()); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// handleModel shows the current model, or switches to the named one.
+func (tb *Bot) handleModel(ctx context.Context, req commandRequest) {
+	if req.arg == "" {
+		_ = tb.sendMessage(ctx, req.chatID, fmt.Sprintf("Current model: `%s`\n\nUsage: %s <name>", tb.agent.Models()[0], modelCommand))
+		return
+	}
+
+	if err := tb.agent.SetModel(req.arg); err != nil {
+		_ = tb.sendMessage(ctx, req.chatID, fmt.Sprintf("Couldn't switch the model: %v", err))
+		return
+	}
+
+	if err := tb.sendMessage(ctx, req.chatID, fmt.Sprintf("Switched to `%s` until the next restart.", req.arg)); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// handleTools lists the tools the sender's role may use, with the first sentence of each description.
+func (tb *Bot) handleTools(ctx context.Context, req commandRequest) {
+	tools := tb.agent.Tools(tb.userScope(req.user))
+	if len(tools) == 0 {
+		_ = tb.sendMessage(ctx, req.chatID, "No tools are available to you.")
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**Tools** (%d):\n", len(tools))
+	for _, tool := range tools {
+		fmt.Fprintf(&sb, "\n• `%s` — %s", tool.Name, firstSentence(tool.Description))
+	}
+
+	if err := tb.sendMessage(ctx, req.chatID, sb.String()); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// firstSentence returns the first sentence or line of a text.
+func firstSentence(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	return text
+}
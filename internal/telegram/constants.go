@@ -1,9 +1,86 @@
 package telegram
 
+import "time"
+
 const (
+	// channelName names the Telegram channel in config and in conversation keys.
+	channelName = "telegram"
+
 	maxTelegramFileSize = 50 * 1024 * 1024
+	maxMessageLength    = 4096
+
+	// Replies longer than this are sent as a Markdown document rather than a series of messages.
+	maxInlineReplyLength = 3 * maxMessageLength
+	maxCaptionPreview    = 300
+	replyDocumentName    = "reply-%s.md"
+	longReplyNotice      = "📄 The reply is long, so it's attached as a document."
+
+	// Telegram rate-limits edits, so streamed text is flushed at most this often.
+	liveEditInterval   = 1500 * time.Millisecond
+	livePlaceholder    = "⏳ Thinking…"
+	liveTruncatePrefix = "…"
+	emptyReplyNotice   = "(no reply)"
+
+	resetCommand  = "/reset"
+	stopCommand   = "/stop"
+	statusCommand = "/status"
+	modelCommand  = "/model"
+	toolsCommand  = "/tools"
+	memoryCommand = "/memory"
+
+	joinCommand   = "/join"
+	inviteCommand = "/invite"
+	usersCommand  = "/users"
+	revokeCommand = "/revoke"
+
+	stopCallbackData = "stop"
+	stopButtonText   = "⏹ Stop"
+
+	// Approval buttons carry "approve:<approval id>:<y|n>".
+	approvalCallbackPrefix = "approve:"
+	approveButtonText      = "✅ Approve"
+	denyButtonText         = "❌ Deny"
+	maxApprovalSummary     = 3000
+
+	// Delete buttons of /memory carry "mem:<user id>:<kind>:<id>", so only the owner can press them.
+	memoryCallbackPrefix = "mem:"
+	maxListedMemories    = 40
+	maxMemoryValueLength = 200
+
+	defaultMaxConcurrentChats = 4
+
+	// Webhook mode.
+	webhookSecretHeader    = "X-Telegram-Bot-Api-Secret-Token"
+	defaultWebhookListen   = ":8443"
+	webhookSecretBytes     = 32
+	maxWebhookBodySize     = 4 * 1024 * 1024
+	webhookReadTimeout     = 30 * time.Second
+	webhookShutdownTimeout = 10 * time.Second
+
+	// Incoming files are saved under the inbox of the user's workspace. The Bot API can't serve files above 20 MB.
+	inboxDirName           = "inbox"
+	inboxTimeFormat        = "20060102-150405"
+	defaultMaxDownloadSize = 20 * 1024 * 1024
+	downloadTimeout        = 2 * time.Minute
+
+	// Speech-to-text. Longer recordings are not transcribed.
+	defaultSTTModel      = "whisper-1"
+	transcribeTimeout    = 5 * time.Minute
+	maxSpeechDuration    = 20 * time.Minute
+	maxTranscriptError   = 512
+	transcriptEchoPrefix = "🎙️ "
+	ffmpegBinary         = "ffmpeg"
+
+	// Replied-to messages are quoted to the agent up to this many runes.
+	maxQuotedLength = 2000
 
 	ContentTypeImage ContentType = "image"
 	ContentTypeDoc   ContentType = "doc"
 	ContentTypeVideo ContentType = "video"
-)
\ No newline at end of file
+)
+
+const (
+	accessUser   commandAccess = iota // Registered users
+	accessAdmin                       // Admins only
+	accessAnyone                      // Also unregistered users
+)
@@ -0,0 +1,154 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// isGroupChat reports whether a chat is a group rather than a private conversation.
+func isGroupChat(chat models.Chat) bool {
+	return chat.Type == models.ChatTypeGroup || chat.Type == models.ChatTypeSupergroup
+}
+
+// threadID returns the message that replies should be threaded under: the triggering message in groups,
+// where several conversations interleave, and none in private chats.
+func threadID(msg *models.Message) int {
+	if isGroupChat(msg.Chat) {
+		return msg.ID
+	}
+	return 0
+}
+
+// addressedText returns the text, or media caption, of a message meant for the bot, with any @mention of the bot removed.
+// Private messages are always meant for the bot. In groups the bot only answers when it is mentioned,
+// replied to, or sent a command that isn't addressed to another bot.
+func (tb *Bot) addressedText(msg *models.Message) (string, bool) {
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		text = strings.TrimSpace(msg.Caption)
+	}
+	if !isGroupChat(msg.Chat) {
+		return text, true
+	}
+
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		_, target, addressed := strings.Cut(command, "@")
+		return text, !addressed || strings.EqualFold(target, tb.username)
+	}
+
+	if stripped, ok := stripMention(text, tb.username); ok {
+		return stripped, true
+	}
+
+	if reply := msg.ReplyToMessage; reply != nil && reply.From != nil && reply.From.ID == tb.userID {
+		return text, true
+	}
+	return "", false
+}
+
+// stripMention removes every @username mention from text and reports whether there was one.
+// A mention must not continue into a longer username, so @vayuu doesn't match @vayuu_dev.
+func stripMention(text, username string) (string, bool) {
+	if username == "" {
+		return text, false
+	}
+
+	mention := "@" + asciiLower(username)
+	lower := asciiLower(text)
+	var sb strings.Builder
+	found := false
+	for {
+		i := strings.Index(lower, mention)
+		if i < 0 {
+			break
+		}
+		end := i + len(mention)
+		if end < len(lower) && isUsernameChar(rune(lower[end])) {
+			sb.WriteString(text[:end])
+		} else {
+			sb.WriteString(text[:i])
+			found = true
+		}
+		text, lower = text[end:], lower[end:]
+	}
+	sb.WriteString(text)
+	return strings.TrimSpace(strings.ReplaceAll(sb.String(), "  ", " ")), found
+}
+
+// asciiLower lowercases ASCII letters only, keeping byte offsets of text and result aligned.
+func asciiLower(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, text)
+}
+
+// isUsernameChar reports whether r may appear in a Telegram username.
+func isUsernameChar(r rune) bool {
+	return r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// agentInput builds the user message for the agent from its text and notes about attached files.
+// Group messages name their sender, and replies include the quoted message so the agent knows what is being referred to.
+func agentInput(msg *models.Message, text string, fileNotes []string) string {
+	var lines []string
+	if isGroupChat(msg.Chat) {
+		lines = append(lines, fmt.Sprintf("[Message from %s in group %q]", senderName(msg.From), msg.Chat.Title))
+	}
+	lines = append(lines, fileNotes...)
+	switch {
+	case text != "" && len(fileNotes) > 0:
+		lines = append(lines, "Caption: "+text)
+	case text != "":
+		lines = append(lines, text)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(lines, "\n"))
+
+	if reply := msg.ReplyToMessage; reply != nil {
+		quoted := reply.Text
+		if quoted == "" {
+			quoted = reply.Caption
+		}
+		if msg.Quote != nil && msg.Quote.Text != "" {
+			quoted = msg.Quote.Text
+		}
+		if quoted = strings.TrimSpace(quoted); quoted != "" {
+			fmt.Fprintf(&sb, "\n\n[In reply to %s]\n%s", senderName(reply.From), quotePrefix(truncateRunes(quoted, maxQuotedLength)))
+		}
+	}
+	return sb.String()
+}
+
+// senderName returns a readable name for a Telegram user.
+func senderName(user *models.User) string {
+	switch {
+	case user == nil:
+		return "someone"
+	case user.Username != "":
+		return "@" + user.Username
+	default:
+		return strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+}
+
+// quotePrefix marks every line of text as quoted.
+func quotePrefix(text string) string {
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ")
+}
+
+// truncateRunes returns the first limit runes of text, followed by an ellipsis when it had to be cut.
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + liveTruncatePrefix
+}
@@ -2,40 +2,188 @@ package telegram
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"path/filepath"
+	"strings"
 
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
 
-// handleMessage is the main handler for incoming Telegram messages. It checks for allowed usernames, updates the current chat ID, sends a typing action, and processes the message using the agent, sending back the response to the user.
+// handleMessage is the main handler for incoming Telegram messages. It ignores group messages not addressed to the bot,
+// dispatches commands to the command router and queues everything else from registered users on the chat's queue.
 func (tb *Bot) handleMessage(ctx context.Context, _ *bot.Bot, update *models.Update) {
-	if update.Message == nil {
+	if update.Message == nil || update.Message.From == nil {
 		return
 	}
 
-	username := update.Message.From.Username
-	if tb.cfg.AllowedUsername != "" && username != tb.cfg.AllowedUsername {
-		slog.Warn("rejected message from unauthorized user", "username", username)
+	msg := update.Message
+	chatID := msg.Chat.ID
+	text, addressed := tb.addressedText(msg)
+	if !addressed || text == "" && msg.ReplyToMessage == nil && len(incomingFiles(msg)) == 0 {
 		return
 	}
+	name, arg := splitCommand(text)
 
-	tb.setCurrentChatID(update.Message.Chat.ID)
+	user, registered := tb.authorize(msg.From)
+	if cmd, ok := tb.command(name); ok {
+		tb.runCommand(ctx, cmd, commandRequest{msg: msg, chatID: chatID, user: user, registered: registered, arg: arg})
+		return
+	}
+	if !registered {
+		slog.Warn("rejected message from unauthorized user", "user_id", msg.From.ID, "username", msg.From.Username, "chat_id", chatID)
+		return
+	}
+
+	tb.queueJob(ctx, msg, func(ctx context.Context) { tb.processMessage(ctx, user, msg, text) })
+}
 
-	if err := tb.sendTypingAction(ctx); err != nil {
+// queueJob adds a job for a message to the chat's queue, acknowledging the message if it has to wait.
+func (tb *Bot) queueJob(ctx context.Context, msg *models.Message, job func(ctx context.Context)) {
+	chatID := msg.Chat.ID
+	if ahead := tb.enqueue(ctx, chatID, job); ahead > 0 {
+		slog.Info("message queued", "chat_id", chatID, "ahead", ahead)
+		if err := tb.replyMessage(ctx, chatID, threadID(msg), fmt.Sprintf("⏳ Queued (%d ahead)", ahead)); err != nil {
+			slog.Debug("failed to acknowledge queued message", "error", err)
+		}
+	}
+}
+
+// processMessage runs the agent on a message within the user's scope and replies with the result.
+// Attached media is saved to the user's inbox first. Groups share one session, and the reply is threaded under the triggering message.
+func (tb *Bot) processMessage(ctx context.Context, user users.User, msg *models.Message, text string) {
+	chatID := msg.Chat.ID
+	runCtx, endRun := tb.beginRun(ctx, chatID, user.ID)
+	defer endRun()
+
+	if err := tb.sendTypingAction(ctx, chatID); err != nil {
 		slog.Debug("typing indicator failed", "error", err)
 	}
 
-	slog.Info("processing message", "user", username, "chat_id", update.Message.Chat.ID)
+	media := tb.receiveMedia(runCtx, msg, tb.userScope(user).WorkDir)
+	if len(media.Transcripts) > 0 {
+		text = strings.TrimSpace(strings.Join(append([]string{text}, media.Transcripts...), "\n\n"))
+	}
+	input := agent.Input{Text: agentInput(msg, text, media.Notes), Images: media.Images}
+
+	slog.Info("processing message", "user_id", user.ID, "role", user.Role, "chat_id", chatID)
+	tb.respond(ctx, runCtx, user, chatID, threadID(msg), input)
+}
+
+// respond runs the agent on input within the user's scope, streaming progress into a live message and sending back the reply and any attachments.
+// runCtx is the run's context from beginRun; the reply is threaded under the message replyTo, or unthreaded if it is zero.
+func (tb *Bot) respond(ctx, runCtx context.Context, user users.User, chatID int64, replyTo int, input agent.Input) {
+	runCtx = agent.WithScope(runCtx, tb.userScope(user))
+	runCtx = agent.WithApprover(runCtx, tb.approver(chatID, user, replyTo))
+
+	live, err := tb.startLiveMessage(ctx, chatID, replyTo)
+	if err != nil {
+		slog.Error("failed to start live message", "error", err)
+		return
+	}
 
-	response, err := tb.agent.RunAgent(ctx, update.Message.Text)
+	reply, err := tb.agent.RunAgent(runCtx, userOrigin(chatID, user), input, live.handleEvent)
 	if err != nil {
 		slog.Error("agent failed", "error", err)
-		_ = tb.sendMessage(ctx, "Sorry, I encountered an error processing your request.")
+		if err := live.finish(ctx, "Sorry, I encountered an error processing your request."); err != nil {
+			slog.Error("failed to send response", "error", err)
+		}
+		return
+	}
+
+	response := reply.Text
+	if reply.Model != "" && !reply.Stopped {
+		response += fmt.Sprintf("\n\n— answered by %s", reply.Model)
+		if reply.Fallback {
+			response += " (fallback)"
+		}
+	}
+
+	if err := live.finish(ctx, response); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+
+	for _, att := range reply.Attachments {
+		if err := tb.sendAttachment(ctx, chatID, att); err != nil {
+			slog.Error("failed to send attachment", "path", att.Path, "error", err)
+			_ = tb.sendMessage(ctx, chatID, fmt.Sprintf("Couldn't send %s: %v", filepath.Base(att.Path), err))
+		}
+	}
+}
+
+// handleReset clears the conversation session of the chat and confirms it to the user.
+func (tb *Bot) handleReset(ctx context.Context, chatID int64) {
+	if err := tb.agent.ResetSession(origin(chatID)); err != nil {
+		slog.Error("failed to reset session", "chat_id", chatID, "error", err)
+		_ = tb.sendMessage(ctx, chatID, "Sorry, I couldn't clear our conversation.")
+		return
+	}
+
+	if err := tb.sendMessage(ctx, chatID, "Conversation cleared. Let's start fresh."); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// handleStop cancels the in-flight run of the chat if user started it or is an admin. The run itself replies with a summary of what it completed.
+func (tb *Bot) handleStop(ctx context.Context, chatID int64, user users.User) {
+	found, allowed := tb.stopRun(chatID, user)
+	if found && allowed {
+		slog.Info("run stopped by user", "chat_id", chatID, "user_id", user.ID)
 		return
 	}
 
-	if err := tb.sendMessage(ctx, response); err != nil {
+	text := "Nothing is running right now."
+	if found {
+		text = "Only the person who started this run or an admin can stop it."
+	}
+	if err := tb.sendMessage(ctx, chatID, text); err != nil {
 		slog.Error("failed to send response", "error", err)
 	}
 }
+
+// handleStopButton handles the inline Stop button shown under a live response.
+func (tb *Bot) handleStopButton(ctx context.Context, b *bot.Bot, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	answer := &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID}
+	user, registered := tb.users.Get(query.From.ID)
+	switch {
+	case !registered:
+		slog.Warn("rejected stop from unauthorized user", "user_id", query.From.ID, "username", query.From.Username)
+		answer.Text = "Not allowed."
+	case query.Message.Message == nil:
+		answer.Text = "This run can no longer be stopped."
+	default:
+		chatID := query.Message.Message.Chat.ID
+		switch found, allowed := tb.stopRun(chatID, user); {
+		case !found:
+			answer.Text = "Nothing is running right now."
+		case !allowed:
+			slog.Warn("rejected stop from user who didn't start the run", "user_id", user.ID, "chat_id", chatID)
+			answer.Text = "Not allowed."
+		default:
+			slog.Info("run stopped by user", "chat_id", chatID, "user_id", user.ID)
+			answer.Text = "Stopping…"
+		}
+	}
+
+	if _, err := b.AnswerCallbackQuery(ctx, answer); err != nil {
+		slog.Debug("failed to answer callback query", "error", err)
+	}
+}
+
+// userScope returns the memory namespace, workspace and tool permissions of a user's agent runs.
+func (tb *Bot) userScope(user users.User) agent.Scope {
+	return agent.Scope{
+		Namespace: user.Namespace,
+		WorkDir:   filepath.Join(tb.cfg.AgentWorkDir, user.Workspace),
+		AllowTool: user.Role.CanUseTool,
+		Confined:  user.Role != users.RoleAdmin,
+	}
+}
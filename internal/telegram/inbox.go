@@ -0,0 +1,215 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// incomingFiles lists the downloadable media attached to a message. Of a photo's sizes only the largest is kept.
+func incomingFiles(msg *models.Message) []incomingFile {
+	var files []incomingFile
+	if n := len(msg.Photo); n > 0 {
+		photo := msg.Photo[n-1]
+		files = append(files, incomingFile{FileID: photo.FileID, Kind: "photo", Name: "photo.jpg", MimeType: "image/jpeg", Size: int64(photo.FileSize)})
+	}
+	if doc := msg.Document; doc != nil {
+		files = append(files, incomingFile{FileID: doc.FileID, Kind: "document", Name: doc.FileName, MimeType: doc.MimeType, Size: doc.FileSize})
+	}
+	if voice := msg.Voice; voice != nil {
+		// Voice notes are OGG/Opus; Telegram names them .oga, which transcription APIs don't recognise.
+		files = append(files, incomingFile{FileID: voice.FileID, Kind: "voice note", Name: "voice.ogg", MimeType: voice.MimeType, Size: voice.FileSize, Duration: voice.Duration, Speech: true})
+	}
+	if audio := msg.Audio; audio != nil {
+		files = append(files, incomingFile{FileID: audio.FileID, Kind: "audio", Name: audio.FileName, MimeType: audio.MimeType, Size: audio.FileSize, Duration: audio.Duration, Speech: true})
+	}
+	if video := msg.Video; video != nil {
+		files = append(files, incomingFile{FileID: video.FileID, Kind: "video", Name: video.FileName, MimeType: video.MimeType, Size: video.FileSize})
+	}
+	return files
+}
+
+// receiveMedia downloads the media of a message into the inbox of a workspace and describes each file,
+// or why it couldn't be saved, for the agent. Voice notes and audio are transcribed when speech-to-text is
+// configured; each transcript is echoed back under the message so the user can check it.
+func (tb *Bot) receiveMedia(ctx context.Context, msg *models.Message, workDir string) incomingMedia {
+	var media incomingMedia
+	for _, file := range incomingFiles(msg) {
+		saved, err := tb.downloadFile(ctx, file, filepath.Join(workDir, inboxDirName))
+		if err != nil {
+			slog.Warn("failed to download incoming file", "kind", file.Kind, "name", file.Name, "error", err)
+			media.Notes = append(media.Notes, fmt.Sprintf("[The user sent a %s that could not be saved: %v]", file.Kind, err))
+			continue
+		}
+
+		slog.Info("saved incoming file", "kind", file.Kind, "path", saved.Path, "mime_type", saved.MimeType, "size", saved.Size)
+		rel, err := filepath.Rel(workDir, saved.Path)
+		if err != nil {
+			rel = saved.Path
+		}
+		note := fmt.Sprintf("[The user sent a %s, saved to %s (%s, %s)]", file.Kind, filepath.ToSlash(rel), saved.MimeType, formatSize(saved.Size))
+
+		if file.Speech && tb.transcriber != nil {
+			transcript, err := tb.transcribe(ctx, file, saved.Path)
+			if err == nil {
+				media.Transcripts = append(media.Transcripts, transcript)
+				_ = tb.replyMessage(ctx, msg.Chat.ID, msg.ID, transcriptEchoPrefix+transcript)
+				continue
+			}
+			slog.Warn("failed to transcribe audio", "path", saved.Path, "error", err)
+			_ = tb.replyMessage(ctx, msg.Chat.ID, msg.ID, fmt.Sprintf("Couldn't transcribe this %s: %v", file.Kind, err))
+			note = fmt.Sprintf("%s [transcription failed: %v]", note, err)
+		}
+
+		media.Notes = append(media.Notes, note)
+		if strings.HasPrefix(saved.MimeType, "image/") {
+			media.Images = append(media.Images, saved.Path)
+		}
+	}
+	return media
+}
+
+// downloadFile fetches a file through the Bot API getFile method and saves it under dir with a unique name.
+func (tb *Bot) downloadFile(ctx context.Context, file incomingFile, dir string) (savedFile, error) {
+	limit := tb.maxDownloadSize()
+	if file.Size > limit {
+		return savedFile{}, fmt.Errorf("file too large (%s, max %s)", formatSize(file.Size), formatSize(limit))
+	}
+
+	info, err := tb.bot.GetFile(ctx, &bot.GetFileParams{FileID: file.FileID})
+	if err != nil {
+		return savedFile{}, fmt.Errorf("get file: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tb.bot.FileDownloadLink(info), nil)
+	if err != nil {
+		return savedFile{}, fmt.Errorf("create download request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The error message of a failed request contains the URL, which includes the bot token.
+		return savedFile{}, errors.New("download failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return savedFile{}, fmt.Errorf("download failed: %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return savedFile{}, fmt.Errorf("create inbox: %w", err)
+	}
+
+	name := file.Name
+	if name == "" {
+		name = filepath.Base(info.FilePath)
+	}
+	out, err := createUnique(dir, time.Now().Format(inboxTimeFormat)+"_"+sanitizeFileName(name))
+	if err != nil {
+		return savedFile{}, err
+	}
+
+	size, err := io.Copy(out, io.LimitReader(resp.Body, limit+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > limit {
+		err = fmt.Errorf("file too large (max %s)", formatSize(limit))
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return savedFile{}, fmt.Errorf("save file: %w", err)
+	}
+
+	return savedFile{Path: out.Name(), MimeType: detectMimeType(out.Name(), file.MimeType), Size: size}, nil
+}
+
+// maxDownloadSize returns the configured limit for incoming files in bytes.
+func (tb *Bot) maxDownloadSize() int64 {
+	if tb.cfg.MaxDownloadMB > 0 {
+		return int64(tb.cfg.MaxDownloadMB) * 1024 * 1024
+	}
+	return defaultMaxDownloadSize
+}
+
+// createUnique creates a new file named name in dir, adding a counter to the name if it is taken.
+func createUnique(dir, name string) (*os.File, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := name
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, candidate), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("create file: %w", err)
+		}
+		return f, nil
+	}
+}
+
+// sanitizeFileName strips directories and characters that are awkward in shell commands from a file name.
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// detectMimeType returns the MIME type reported by Telegram, falling back to the file extension and then to sniffing the content.
+func detectMimeType(path, reported string) string {
+	if reported != "" {
+		return reported
+	}
+	if byExt := mime.TypeByExtension(filepath.Ext(path)); byExt != "" {
+		return byExt
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return http.DetectContentType(head[:n])
+}
+
+// formatSize formats a byte count for people.
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
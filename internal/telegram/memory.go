@@ -0,0 +1,164 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/memory"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// handleMemory lists the profile entries and preferences remembered about the sender, each with a delete button.
+// The list is personal, so it is only shown in private chats.
+func (tb *Bot) handleMemory(ctx context.Context, req commandRequest) {
+	if isGroupChat(req.msg.Chat) {
+		_ = tb.replyMessage(ctx, req.chatID, req.msg.ID, "Send /memory to me in a private chat.")
+		return
+	}
+
+	text, keyboard, err := tb.memoryList(ctx, req.user)
+	if err != nil {
+		slog.Warn("failed to list memories", "user_id", req.user.ID, "error", err)
+		_ = tb.sendMessage(ctx, req.chatID, fmt.Sprintf("Couldn't read your memories: %v", err))
+		return
+	}
+
+	params := &bot.SendMessageParams{ChatID: req.chatID, Text: text, ParseMode: models.ParseModeHTML}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	if _, err := tb.bot.SendMessage(ctx, params); err != nil {
+		slog.Error("failed to send response", "error", err)
+	}
+}
+
+// memoryList renders the known facts of a user as HTML, with a keyboard holding one delete button per fact.
+// The keyboard is nil when there is nothing to delete.
+func (tb *Bot) memoryList(ctx context.Context, user users.User) (string, *models.InlineKeyboardMarkup, error) {
+	mgr := tb.agent.Memory()
+	if mgr == nil {
+		return "", nil, errors.New("long-term memory is not available")
+	}
+
+	facts, err := mgr.KnownFacts(memory.WithNamespace(ctx, user.Namespace))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(facts) == 0 {
+		return "I don't know anything about you yet.", nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>What I know about you</b>\n")
+	keyboard := &models.InlineKeyboardMarkup{}
+	for i, fact := range facts {
+		if i == maxListedMemories {
+			fmt.Fprintf(&sb, "\n…and %d more. Delete some to see the rest.", len(facts)-i)
+			break
+		}
+
+		label := fact.Key
+		if fact.Kind == memory.KnownPreference && fact.Category != "" {
+			label = fact.Category + "/" + fact.Key
+		}
+		fmt.Fprintf(&sb, "\n%d. <b>%s</b>: %s", i+1, html.EscapeString(label), html.EscapeString(truncateRunes(fact.Value, maxMemoryValueLength)))
+
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("🗑 %d. %s", i+1, truncateRunes(label, 40)),
+			CallbackData: fmt.Sprintf("%s%d:%s:%d", memoryCallbackPrefix, user.ID, fact.Kind, fact.ID),
+		}})
+	}
+	return sb.String(), keyboard, nil
+}
+
+// handleMemoryButton deletes the fact behind a /memory delete button and refreshes the list.
+// Only the user the list belongs to may press its buttons.
+func (tb *Bot) handleMemoryButton(ctx context.Context, b *bot.Bot, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	answer := &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID}
+	defer func() {
+		if _, err := b.AnswerCallbackQuery(ctx, answer); err != nil {
+			slog.Debug("failed to answer callback query", "error", err)
+		}
+	}()
+
+	ownerID, kind, id, ok := parseMemoryCallback(query.Data)
+	user, registered := tb.users.Get(query.From.ID)
+	switch {
+	case !ok:
+		answer.Text = "Unknown button."
+		return
+	case !registered || user.ID != ownerID:
+		slog.Warn("rejected memory deletion", "user_id", query.From.ID, "owner_id", ownerID)
+		answer.Text = "Not allowed."
+		return
+	}
+
+	mgr := tb.agent.Memory()
+	if mgr == nil {
+		answer.Text = "Long-term memory is not available."
+		return
+	}
+	fact, err := mgr.Forget(memory.WithNamespace(ctx, user.Namespace), kind, id)
+	if err != nil {
+		slog.Warn("failed to forget memory", "user_id", user.ID, "kind", kind, "id", id, "error", err)
+		answer.Text = fmt.Sprintf("Couldn't delete: %v", err)
+	} else {
+		answer.Text = truncateRunes("Forgotten: "+fact.Key, 200)
+	}
+
+	if query.Message.Message == nil {
+		return
+	}
+	text, keyboard, err := tb.memoryList(ctx, user)
+	if err != nil {
+		slog.Warn("failed to refresh memories", "user_id", user.ID, "error", err)
+		return
+	}
+	params := &bot.EditMessageTextParams{
+		ChatID:    query.Message.Message.Chat.ID,
+		MessageID: query.Message.Message.ID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	if _, err := b.EditMessageText(ctx, params); err != nil && !isNotModified(err) {
+		slog.Debug("failed to refresh memory list", "error", err)
+	}
+}
+
+// parseMemoryCallback splits the data of a delete button into the owner's user ID, the kind of fact and its ID.
+func parseMemoryCallback(data string) (int64, memory.KnownFactKind, int64, bool) {
+	parts := strings.Split(strings.TrimPrefix(data, memoryCallbackPrefix), ":")
+	if len(parts) != 3 {
+		return 0, "", 0, false
+	}
+
+	ownerID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", 0, false
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, "", 0, false
+	}
+
+	kind := memory.KnownFactKind(parts[1])
+	if kind != memory.KnownProfile && kind != memory.KnownPreference {
+		return 0, "", 0, false
+	}
+	return ownerID, kind, id, true
+}
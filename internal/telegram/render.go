@@ -0,0 +1,306 @@
+package telegram
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	listPattern    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^ {0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	fencePattern   = regexp.MustCompile("^ {0,3}(```+|~~~+)[ \t]*([\\w+#.-]*)[^\n`]*(?:\n|$)")
+)
+
+// renderReply converts the model's CommonMark into Telegram HTML messages of at most limit characters.
+// Messages break between paragraphs and code blocks; a block too long for one message is split and
+// code blocks are re-fenced on both sides, so markup never spans two messages.
+// Markup the renderer doesn't understand, or that isn't closed, is kept as literal text.
+func renderReply(markdown string, limit int) []renderedChunk {
+	var chunks []renderedChunk
+	var htmlParts, plainParts []string
+	size := 0
+
+	flush := func() {
+		if len(htmlParts) > 0 {
+			chunks = append(chunks, renderedChunk{HTML: strings.Join(htmlParts, "\n\n"), Plain: strings.Join(plainParts, "\n\n")})
+		}
+		htmlParts, plainParts, size = nil, nil, 0
+	}
+
+	for _, block := range markdownBlocks(markdown) {
+		for _, piece := range fitBlock(block, limit) {
+			rendered := renderBlock(piece)
+			n := utf8.RuneCountInString(rendered)
+			if size > 0 && size+2+n > limit {
+				flush()
+			}
+			htmlParts = append(htmlParts, rendered)
+			plainParts = append(plainParts, piece)
+			size += n + 2
+		}
+	}
+	flush()
+	return chunks
+}
+
+// markdownBlocks splits markdown into paragraphs and fenced code blocks.
+func markdownBlocks(markdown string) []string {
+	var blocks, current []string
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(current, "\n")); text != "" {
+			blocks = append(blocks, strings.Trim(strings.Join(current, "\n"), "\n"))
+		}
+		current = nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			flush()
+			current = append(current, line)
+			for i++; i < len(lines); i++ {
+				current = append(current, lines[i])
+				if isClosingFence(lines[i], m[1]) {
+					break
+				}
+			}
+			flush()
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
+
+// isClosingFence reports whether line closes a code block opened with fence.
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence[:1]) && len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// fitBlock splits a block until every piece renders within limit, preferring line breaks, then spaces.
+func fitBlock(block string, limit int) []string {
+	if utf8.RuneCountInString(renderBlock(block)) <= limit {
+		return []string{block}
+	}
+
+	if m := fencePattern.FindStringSubmatch(block); m != nil {
+		lines := strings.Split(block, "\n")
+		open := lines[0]
+		body := lines[1:]
+		if n := len(body); n > 0 && isClosingFence(body[n-1], m[1]) {
+			body = body[:n-1]
+		}
+		code := strings.Join(body, "\n")
+		if utf8.RuneCountInString(code) <= 1 {
+			return []string{block}
+		}
+		first, second := splitNear(code)
+		closing := m[1]
+		return append(fitBlock(open+"\n"+first+"\n"+closing, limit), fitBlock(open+"\n"+second+"\n"+closing, limit)...)
+	}
+
+	if utf8.RuneCountInString(block) <= 1 {
+		return []string{block}
+	}
+	first, second := splitNear(block)
+	return append(fitBlock(first, limit), fitBlock(second, limit)...)
+}
+
+// splitNear splits text in two near its middle, at a newline if there is one in the middle half, else at a space.
+func splitNear(text string) (string, string) {
+	runes := []rune(text)
+	mid := len(runes) / 2
+	for _, sep := range []rune{'\n', ' '} {
+		for d := 0; d < mid/2; d++ {
+			for _, i := range []int{mid - d, mid + d} {
+				if i > 0 && i < len(runes)-1 && runes[i] == sep {
+					return string(runes[:i]), string(runes[i+1:])
+				}
+			}
+		}
+	}
+	return string(runes[:mid]), string(runes[mid:])
+}
+
+// renderBlock renders one paragraph or code block as Telegram HTML.
+func renderBlock(block string) string {
+	if m := fencePattern.FindStringSubmatch(block); m != nil {
+		lines := strings.Split(block, "\n")[1:]
+		if n := len(lines); n > 0 && isClosingFence(lines[n-1], m[1]) {
+			lines = lines[:n-1]
+		}
+		code := html.EscapeString(strings.Join(lines, "\n"))
+		if m[2] != "" {
+			return `<pre><code class="language-` + html.EscapeString(m[2]) + `">` + code + "</code></pre>"
+		}
+		return "<pre>" + code + "</pre>"
+	}
+
+	var out, quote []string
+	flushQuote := func() {
+		if len(quote) > 0 {
+			out = append(out, "<blockquote>"+strings.Join(quote, "\n")+"</blockquote>")
+			quote = nil
+		}
+	}
+
+	for _, line := range strings.Split(block, "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimLeft(line, " "), ">"); ok {
+			quote = append(quote, renderInline(strings.TrimPrefix(rest, " ")))
+			continue
+		}
+		flushQuote()
+
+		switch {
+		case rulePattern.MatchString(line):
+			out = append(out, "──────────")
+		case headingPattern.MatchString(line):
+			out = append(out, "<b>"+renderInline(headingPattern.FindStringSubmatch(line)[1])+"</b>")
+		case listPattern.MatchString(line):
+			m := listPattern.FindStringSubmatch(line)
+			out = append(out, m[1]+"• "+renderInline(m[2]))
+		default:
+			out = append(out, renderInline(line))
+		}
+	}
+	flushQuote()
+	return strings.Join(out, "\n")
+}
+
+// inlineStyles maps paired delimiters to the HTML tags they become, longest delimiters first.
+var inlineStyles = []struct {
+	delim string
+	tag   string
+}{
+	{"**", "b"},
+	{"__", "b"},
+	{"~~", "s"},
+	{"*", "i"},
+	{"_", "i"},
+}
+
+// renderInline renders the inline markup of a line: code spans, emphasis, strikethrough and links.
+func renderInline(text string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && isASCIIPunct(rest[1]):
+			sb.WriteString(html.EscapeString(rest[1:2]))
+			i += 2
+			continue
+
+		case rest[0] == '`':
+			ticks := len(rest) - len(strings.TrimLeft(rest, "`"))
+			if end := strings.Index(rest[ticks:], rest[:ticks]); end >= 0 {
+				code := strings.TrimSpace(rest[ticks : ticks+end])
+				sb.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += 2*ticks + end
+				continue
+			}
+			sb.WriteString(html.EscapeString(rest[:ticks]))
+			i += ticks
+			continue
+
+		case rest[0] == '[':
+			if label, url, n, ok := parseLink(rest); ok {
+				sb.WriteString(`<a href="` + html.EscapeString(url) + `">` + renderInline(label) + "</a>")
+				i += n
+				continue
+			}
+		}
+
+		if tag, inner, n, ok := parseEmphasis(text, i); ok {
+			sb.WriteString("<" + tag + ">" + renderInline(inner) + "</" + tag + ">")
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		sb.WriteString(html.EscapeString(string(r)))
+		i += size
+	}
+	return sb.String()
+}
+
+// parseEmphasis matches a delimited span starting at text[i] and returns its tag, content and length.
+// Spans must not start or end with a space, and underscores only count at word boundaries so snake_case stays intact.
+func parseEmphasis(text string, i int) (string, string, int, bool) {
+	rest := text[i:]
+	for _, style := range inlineStyles {
+		d := style.delim
+		if !strings.HasPrefix(rest, d) || len(rest) <= 2*len(d) {
+			continue
+		}
+		if d[0] == '_' && i > 0 && isWordByte(text[i-1]) {
+			continue
+		}
+
+		body := rest[len(d):]
+		if body[0] == ' ' || strings.HasPrefix(body, d[:1]) {
+			continue
+		}
+		for from := 0; from < len(body); {
+			end := strings.Index(body[from:], d)
+			if end < 0 {
+				break
+			}
+			end += from
+			after := end + len(d)
+			closes := end > 0 && body[end-1] != ' ' &&
+				(len(d) == 2 || after >= len(body) || body[after] != d[0]) &&
+				(d[0] != '_' || after >= len(body) || !isWordByte(body[after]))
+			if closes {
+				return style.tag, body[:end], len(d) + after, true
+			}
+			from = end + 1
+		}
+	}
+	return "", "", 0, false
+}
+
+// parseLink matches [label](url) with an http, https, mailto or tg URL.
+func parseLink(text string) (string, string, int, bool) {
+	closeLabel := strings.Index(text, "](")
+	if closeLabel < 0 {
+		return "", "", 0, false
+	}
+	closeURL := strings.IndexByte(text[closeLabel+2:], ')')
+	if closeURL < 0 {
+		return "", "", 0, false
+	}
+
+	label := text[1:closeLabel]
+	url := strings.TrimSpace(text[closeLabel+2 : closeLabel+2+closeURL])
+	if label == "" || strings.ContainsAny(url, " \n") || strings.Contains(label, "[") {
+		return "", "", 0, false
+	}
+	for _, scheme := range []string{"http://", "https://", "mailto:", "tg://"} {
+		if strings.HasPrefix(strings.ToLower(url), scheme) {
+			return label, url, closeLabel + 3 + closeURL, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// isWordByte reports whether b is an ASCII letter, digit or underscore, or part of a multi-byte character.
+func isWordByte(b byte) bool {
+	return b >= utf8.RuneSelf || b == '_' || b < unicode.MaxASCII && (unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b)))
+}
+
+// isASCIIPunct reports whether b is ASCII punctuation, which a backslash escapes in CommonMark.
+func isASCIIPunct(b byte) bool {
+	return b < utf8.RuneSelf && unicode.IsPunct(rune(b)) || strings.IndexByte("$+<=>^`|~", b) >= 0
+}
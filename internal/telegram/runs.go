@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
+)
+
+// enqueue adds a job to the chat's queue and returns how many jobs are ahead of it.
+// Jobs of one chat run one after another; different chats run in parallel up to the slot limit.
+func (tb *Bot) enqueue(ctx context.Context, chatID int64, job func(ctx context.Context)) int {
+	tb.queueMu.Lock()
+	defer tb.queueMu.Unlock()
+
+	q, ok := tb.queues[chatID]
+	if !ok {
+		q = &chatQueue{}
+		tb.queues[chatID] = q
+	}
+
+	ahead := len(q.pending)
+	if q.running {
+		ahead++
+	}
+	q.pending = append(q.pending, job)
+
+	if !q.running {
+		q.running = true
+		go tb.drain(ctx, chatID, q)
+	}
+	return ahead
+}
+
+// drain runs the queued jobs of a chat until the queue is empty.
+func (tb *Bot) drain(ctx context.Context, chatID int64, q *chatQueue) {
+	for {
+		tb.queueMu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			delete(tb.queues, chatID)
+			tb.queueMu.Unlock()
+			return
+		}
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		tb.queueMu.Unlock()
+
+		tb.slots <- struct{}{}
+		job(ctx)
+		<-tb.slots
+	}
+}
+
+// beginRun registers a cancellable context for the agent run of a chat on behalf of a user.
+// The returned function must be called when the run ends.
+func (tb *Bot) beginRun(ctx context.Context, chatID, requesterID int64) (context.Context, func()) {
+	runCtx, cancel := context.WithCancel(ctx)
+
+	tb.queueMu.Lock()
+	tb.runs[chatID] = activeRun{cancel: cancel, requesterID: requesterID}
+	tb.queueMu.Unlock()
+
+	return runCtx, func() {
+		tb.queueMu.Lock()
+		delete(tb.runs, chatID)
+		tb.queueMu.Unlock()
+		cancel()
+	}
+}
+
+// stopRun cancels the in-flight run of a chat on behalf of user. It reports whether there was a run,
+// and whether the user may stop it: only the user the run is for and admins may.
+// Messages queued behind it are still processed.
+func (tb *Bot) stopRun(chatID int64, user users.User) (found, allowed bool) {
+	tb.queueMu.Lock()
+	defer tb.queueMu.Unlock()
+
+	run, ok := tb.runs[chatID]
+	if !ok {
+		return false, false
+	}
+	if user.ID != run.requesterID && user.Role != users.RoleAdmin {
+		return true, false
+	}
+	run.cancel()
+	return true, true
+}
+
+// queueStats returns how many chats are working through their queue and how many jobs are waiting across all chats.
+func (tb *Bot) queueStats() (int, int) {
+	tb.queueMu.Lock()
+	defer tb.queueMu.Unlock()
+
+	busy, waiting := 0, 0
+	for _, q := range tb.queues {
+		if q.running {
+			busy++
+		}
+		waiting += len(q.pending)
+	}
+	return busy, waiting
+}
@@ -6,63 +6,118 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
 
-// sendMessage sends a text message to the current chat ID using the Telegram bot API, with MarkdownV1 parsing enabled.
-func (tb *Bot) sendMessage(ctx context.Context, text string) error {
+// sendMessage sends Markdown text to the given chat, rendered and split as described by replyMessage.
+func (tb *Bot) sendMessage(ctx context.Context, chatID int64, text string) error {
+	return tb.replyMessage(ctx, chatID, 0, text)
+}
+
+// replyMessage renders Markdown text into Telegram HTML and sends it threaded under the message replyTo, or unthreaded if it is zero.
+// Text longer than one message is split at paragraph and code block boundaries; very long text is attached as a Markdown document.
+func (tb *Bot) replyMessage(ctx context.Context, chatID int64, replyTo int, text string) error {
+	if utf8.RuneCountInString(text) > maxInlineReplyLength {
+		return tb.sendReplyDocument(ctx, chatID, text)
+	}
+
+	for _, chunk := range renderReply(text, maxMessageLength) {
+		if err := tb.sendRendered(ctx, chatID, replyTo, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendRendered sends one rendered message, falling back to its plain text if Telegram rejects the HTML.
+func (tb *Bot) sendRendered(ctx context.Context, chatID int64, replyTo int, chunk renderedChunk) error {
 	_, err := tb.bot.SendMessage(ctx, &bot.SendMessageParams{
-		ParseMode: models.ParseModeMarkdownV1,
-		ChatID:    tb.currentChatID,
-		Text:      text,
+		ChatID:          chatID,
+		Text:            chunk.HTML,
+		ParseMode:       models.ParseModeHTML,
+		ReplyParameters: replyParameters(replyTo),
 	})
-	return err
+	if err == nil {
+		return nil
+	}
+
+	slog.Debug("html send failed, retrying as plain text", "error", err)
+	if _, err := tb.bot.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: chunk.Plain, ReplyParameters: replyParameters(replyTo)}); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	return nil
 }
 
-// SendContent is a public method that allows sending various types of content (images, videos, documents) to the current chat. It detects the content type, validates it, and calls the appropriate method to send the content using the Telegram bot API.
-func (tb *Bot) sendTypingAction(ctx context.Context) error {
+// sendReplyDocument sends a long reply as a Markdown document, captioned with the start of the reply.
+func (tb *Bot) sendReplyDocument(ctx context.Context, chatID int64, text string) error {
+	dir, err := os.MkdirTemp("", "vayuu-reply-")
+	if err != nil {
+		return fmt.Errorf("create reply document: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, fmt.Sprintf(replyDocumentName, time.Now().Format(inboxTimeFormat)))
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		return fmt.Errorf("write reply document: %w", err)
+	}
+
+	caption := strings.TrimSpace(text)
+	if i := strings.Index(caption, "\n\n"); i > 0 {
+		caption = caption[:i]
+	}
+	return tb.sendDocument(ctx, chatID, path, truncateRunes(caption, maxCaptionPreview))
+}
+
+// sendTypingAction shows the typing indicator in the given chat using the Telegram bot API.
+func (tb *Bot) sendTypingAction(ctx context.Context, chatID int64) error {
 	_, err := tb.bot.SendChatAction(ctx, &bot.SendChatActionParams{
-		ChatID: tb.currentChatID,
+		ChatID: chatID,
 		Action: models.ChatActionTyping,
 	})
 	return err
 }
 
-// SendContent is a public method that allows sending various types of content (images, videos, documents) to the current chat. It detects the content type, validates it, and calls the appropriate method to send the content using the Telegram bot API.
-func (tb *Bot) SendContent(content, caption string) error {
-	if tb.currentChatID == 0 {
+// sendAttachment sends a file produced by a tool to a chat, honouring the tool's type hint when it matches the file.
+func (tb *Bot) sendAttachment(ctx context.Context, chatID int64, att agent.Attachment) error {
+	if chatID == 0 {
 		return fmt.Errorf("no active chat")
 	}
 
-	detectedType, err := DetectContentType(content)
+	detectedType, err := DetectContentType(att.Path)
 	if err != nil {
 		return fmt.Errorf("detect content type: %w", err)
 	}
 
-	contentType, err := ValidateContentType("", detectedType)
-	if err != nil {
-		slog.Warn("content type validation failed, using detected", "error", err)
-		contentType = detectedType
+	contentType := detectedType
+	if att.Type != "" {
+		contentType, err = ValidateContentType(ContentType(att.Type), detectedType)
+		if err != nil {
+			slog.Warn("content type validation failed, using detected", "error", err)
+		}
 	}
 
-	slog.Debug("sending content", "type", contentType, "content_len", len(content))
+	slog.Debug("sending content", "type", contentType, "path", att.Path)
 
 	switch contentType {
 	case ContentTypeImage:
-		return tb.sendPhoto(content, caption)
+		return tb.sendPhoto(ctx, chatID, att.Path, att.Caption)
 	case ContentTypeVideo:
-		return tb.sendVideo(content, caption)
+		return tb.sendVideo(ctx, chatID, att.Path, att.Caption)
 	case ContentTypeDoc:
-		return tb.sendDocument(content, caption)
+		return tb.sendDocument(ctx, chatID, att.Path, att.Caption)
 	default:
-		return tb.sendDocument(content, caption)
+		return tb.sendDocument(ctx, chatID, att.Path, att.Caption)
 	}
 }
 
-// sendPhoto sends an image file to the current chat ID using the Telegram bot API, with an optional caption.
-func (tb *Bot) sendPhoto(filePath, caption string) error {
+// sendPhoto sends an image file to the given chat using the Telegram bot API, with an optional caption.
+func (tb *Bot) sendPhoto(ctx context.Context, chatID int64, filePath, caption string) error {
 	if err := validateFileForUpload(filePath); err != nil {
 		return err
 	}
@@ -74,7 +129,7 @@ func (tb *Bot) sendPhoto(filePath, caption string) error {
 	defer f.Close()
 
 	params := &bot.SendPhotoParams{
-		ChatID: tb.currentChatID,
+		ChatID: chatID,
 		Photo: &models.InputFileUpload{
 			Filename: filepath.Base(filePath),
 			Data:     f,
@@ -84,16 +139,16 @@ func (tb *Bot) sendPhoto(filePath, caption string) error {
 		params.Caption = caption
 	}
 
-	if _, err := tb.bot.SendPhoto(context.Background(), params); err != nil {
+	if _, err := tb.bot.SendPhoto(ctx, params); err != nil {
 		return fmt.Errorf("send photo: %w", err)
 	}
 
-	slog.Info("photo sent", "chat_id", tb.currentChatID, "path", filePath)
+	slog.Info("photo sent", "chat_id", chatID, "path", filePath)
 	return nil
 }
 
-// sendVideo sends a video file to the current chat ID using the Telegram bot API, with an optional caption.
-func (tb *Bot) sendVideo(filePath, caption string) error {
+// sendVideo sends a video file to the given chat using the Telegram bot API, with an optional caption.
+func (tb *Bot) sendVideo(ctx context.Context, chatID int64, filePath, caption string) error {
 	if err := validateFileForUpload(filePath); err != nil {
 		return err
 	}
@@ -105,7 +160,7 @@ func (tb *Bot) sendVideo(filePath, caption string) error {
 	defer f.Close()
 
 	params := &bot.SendVideoParams{
-		ChatID: tb.currentChatID,
+		ChatID: chatID,
 		Video: &models.InputFileUpload{
 			Filename: filepath.Base(filePath),
 			Data:     f,
@@ -115,16 +170,16 @@ func (tb *Bot) sendVideo(filePath, caption string) error {
 		params.Caption = caption
 	}
 
-	if _, err := tb.bot.SendVideo(context.Background(), params); err != nil {
+	if _, err := tb.bot.SendVideo(ctx, params); err != nil {
 		return fmt.Errorf("send video: %w", err)
 	}
 
-	slog.Info("video sent", "chat_id", tb.currentChatID, "path", filePath)
+	slog.Info("video sent", "chat_id", chatID, "path", filePath)
 	return nil
 }
 
-// sendDocument sends a document file to the current chat ID using the Telegram bot API, with an optional caption.
-func (tb *Bot) sendDocument(filePath, caption string) error {
+// sendDocument sends a document file to the given chat using the Telegram bot API, with an optional caption.
+func (tb *Bot) sendDocument(ctx context.Context, chatID int64, filePath, caption string) error {
 	if err := validateFileForUpload(filePath); err != nil {
 		return err
 	}
@@ -136,7 +191,7 @@ func (tb *Bot) sendDocument(filePath, caption string) error {
 	defer f.Close()
 
 	params := &bot.SendDocumentParams{
-		ChatID: tb.currentChatID,
+		ChatID: chatID,
 		Document: &models.InputFileUpload{
 			Filename: filepath.Base(filePath),
 			Data:     f,
@@ -146,10 +201,10 @@ func (tb *Bot) sendDocument(filePath, caption string) error {
 		params.Caption = caption
 	}
 
-	if _, err := tb.bot.SendDocument(context.Background(), params); err != nil {
+	if _, err := tb.bot.SendDocument(ctx, params); err != nil {
 		return fmt.Errorf("send document: %w", err)
 	}
 
-	slog.Info("document sent", "chat_id", tb.currentChatID, "path", filePath)
+	slog.Info("document sent", "chat_id", chatID, "path", filePath)
 	return nil
 }
@@ -0,0 +1,171 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// startLiveMessage sends a placeholder message, threaded under replyTo unless it is zero, and starts flushing
+// streamed agent output into it. The caller must call finish to stop the flusher and render the final reply.
+func (tb *Bot) startLiveMessage(ctx context.Context, chatID int64, replyTo int) (*liveMessage, error) {
+	msg, err := tb.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		Text:            livePlaceholder,
+		ReplyMarkup:     stopKeyboard(),
+		ReplyParameters: replyParameters(replyTo),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("send placeholder: %w", err)
+	}
+
+	lm := &liveMessage{
+		tb:        tb,
+		chatID:    chatID,
+		messageID: msg.ID,
+		shown:     livePlaceholder,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go lm.run(ctx)
+	return lm, nil
+}
+
+// handleEvent records agent progress; the flusher picks it up on its next tick.
+func (lm *liveMessage) handleEvent(event agent.Event) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	switch event.Kind {
+	case agent.EventText:
+		lm.text = event.Text
+	case agent.EventToolStart:
+		lm.status = fmt.Sprintf("⚙️ running %s…", event.Tool)
+	case agent.EventApproval:
+		lm.status = fmt.Sprintf("⏸ waiting for approval of %s…", event.Tool)
+	case agent.EventToolEnd:
+		lm.status = ""
+	}
+}
+
+// run periodically edits the message with the latest streamed text until stopped.
+func (lm *liveMessage) run(ctx context.Context) {
+	defer close(lm.done)
+
+	ticker := time.NewTicker(liveEditInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-lm.stop:
+			return
+		case <-ticker.C:
+			lm.flush(ctx)
+		}
+	}
+}
+
+// flush renders the current progress as plain text, skipping the edit when nothing changed.
+// Markdown is only applied to the final reply since partial output often has unbalanced markup.
+func (lm *liveMessage) flush(ctx context.Context) {
+	lm.mu.Lock()
+	text := lm.text
+	if lm.status != "" {
+		text = strings.TrimSpace(text + "\n\n" + lm.status)
+	}
+	lm.mu.Unlock()
+
+	text = tailRunes(text, maxMessageLength)
+	if text == "" || text == lm.shown {
+		return
+	}
+
+	if err := lm.edit(ctx, text, "", stopKeyboard()); err != nil {
+		slog.Debug("live message edit failed", "chat_id", lm.chatID, "error", err)
+		return
+	}
+	lm.shown = text
+}
+
+// finish stops streaming and replaces the message with the final reply rendered as HTML, removing the Stop button.
+// Replies longer than one message continue in follow-up messages; very long ones are attached as a document.
+// An empty reply leaves a short note so the placeholder doesn't stay behind.
+func (lm *liveMessage) finish(ctx context.Context, final string) error {
+	close(lm.stop)
+	<-lm.done
+
+	if utf8.RuneCountInString(final) > maxInlineReplyLength {
+		if err := lm.edit(ctx, longReplyNotice, "", nil); err != nil && !isNotModified(err) {
+			slog.Debug("failed to replace live message", "error", err)
+		}
+		return lm.tb.sendReplyDocument(ctx, lm.chatID, final)
+	}
+
+	chunks := renderReply(final, maxMessageLength)
+	if len(chunks) == 0 {
+		if err := lm.edit(ctx, emptyReplyNotice, "", nil); err != nil && !isNotModified(err) {
+			return fmt.Errorf("edit final message: %w", err)
+		}
+		return nil
+	}
+
+	if err := lm.edit(ctx, chunks[0].HTML, models.ParseModeHTML, nil); err != nil {
+		slog.Debug("html edit failed, retrying as plain text", "error", err)
+		if err := lm.edit(ctx, chunks[0].Plain, "", nil); err != nil && !isNotModified(err) {
+			return fmt.Errorf("edit final message: %w", err)
+		}
+	}
+
+	for _, chunk := range chunks[1:] {
+		if err := lm.tb.sendRendered(ctx, lm.chatID, 0, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// edit replaces the text of the live message. A nil keyboard removes any inline buttons.
+func (lm *liveMessage) edit(ctx context.Context, text string, parseMode models.ParseMode, keyboard models.ReplyMarkup) error {
+	params := &bot.EditMessageTextParams{
+		ChatID:    lm.chatID,
+		MessageID: lm.messageID,
+		Text:      text,
+		ParseMode: parseMode,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, err := lm.tb.bot.EditMessageText(ctx, params)
+	return err
+}
+
+// stopKeyboard is the inline keyboard with the Stop button shown while a run is in progress.
+func stopKeyboard() *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: stopButtonText, CallbackData: stopCallbackData}},
+		},
+	}
+}
+
+// replyParameters threads a message under replyTo. Zero means no thread; a deleted target doesn't fail the send.
+func replyParameters(replyTo int) *models.ReplyParameters {
+	if replyTo == 0 {
+		return nil
+	}
+	return &models.ReplyParameters{MessageID: replyTo, AllowSendingWithoutReply: true}
+}
+
+// isNotModified reports whether Telegram rejected an edit because the text is unchanged.
+func isNotModified(err error) bool {
+	return strings.Contains(err.Error(), "message is not modified")
+}
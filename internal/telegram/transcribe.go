@@ -0,0 +1,175 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Shreehari-Acharya/vayuu/config"
+)
+
+// newTranscriber creates the speech-to-text backend from the config: a local whisper binary when one is set,
+// otherwise an OpenAI-compatible endpoint. It returns nil when neither is configured.
+func newTranscriber(cfg *config.Config) (transcriber, error) {
+	switch {
+	case cfg.WhisperBinary != "":
+		binary, err := exec.LookPath(cfg.WhisperBinary)
+		if err != nil {
+			return nil, fmt.Errorf("whisper binary: %w", err)
+		}
+		if _, err := exec.LookPath(ffmpegBinary); err != nil {
+			return nil, fmt.Errorf("whisper transcription needs %s to convert voice notes: %w", ffmpegBinary, err)
+		}
+		slog.Info("speech-to-text enabled", "backend", "whisper", "binary", binary, "model", cfg.WhisperModelPath)
+		return &whisperTranscriber{binary: binary, modelPath: cfg.WhisperModelPath, language: cfg.STTLanguage}, nil
+
+	case cfg.STTBaseURL != "":
+		model := cfg.STTModel
+		if model == "" {
+			model = defaultSTTModel
+		}
+		url := strings.TrimSuffix(cfg.STTBaseURL, "/") + "/audio/transcriptions"
+		slog.Info("speech-to-text enabled", "backend", "http", "url", url, "model", model)
+		return &httpTranscriber{
+			url:      url,
+			apiKey:   cfg.STTApiKey,
+			model:    model,
+			language: cfg.STTLanguage,
+			client:   &http.Client{Timeout: transcribeTimeout},
+		}, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// transcribe turns a saved voice note or audio file into text, refusing recordings that are too long.
+func (tb *Bot) transcribe(ctx context.Context, file incomingFile, path string) (string, error) {
+	if limit := int(maxSpeechDuration / time.Second); file.Duration > limit {
+		return "", fmt.Errorf("recording too long (%ds, max %ds)", file.Duration, limit)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transcribeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	text, err := tb.transcriber.Transcribe(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("no speech recognised")
+	}
+
+	slog.Info("transcribed audio", "path", path, "duration", time.Since(start), "chars", len(text))
+	return text, nil
+}
+
+// Transcribe uploads the audio file as multipart form data and returns the recognised text.
+func (t *httpTranscriber) Transcribe(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", fmt.Errorf("read audio: %w", err)
+	}
+	fields := map[string]string{"model": t.model, "response_format": "json"}
+	if t.language != "" {
+		fields["language"] = t.language
+	}
+	for key, value := range fields {
+		if err := form.WriteField(key, value); err != nil {
+			return "", err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read transcription response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("transcription endpoint returned %s: %s", resp.Status, truncateRunes(strings.TrimSpace(string(data)), maxTranscriptError))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("parse transcription response: %w", err)
+	}
+	return result.Text, nil
+}
+
+// Transcribe converts the audio to the 16 kHz mono WAV whisper.cpp expects and runs the binary on it.
+func (t *whisperTranscriber) Transcribe(ctx context.Context, path string) (string, error) {
+	wav, err := os.CreateTemp("", "vayuu-stt-*.wav")
+	if err != nil {
+		return "", err
+	}
+	wav.Close()
+	defer os.Remove(wav.Name())
+
+	convert := exec.CommandContext(ctx, ffmpegBinary, "-nostdin", "-loglevel", "error", "-y",
+		"-i", path, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", wav.Name())
+	if out, err := convert.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("convert audio: %v: %s", err, truncateRunes(strings.TrimSpace(string(out)), maxTranscriptError))
+	}
+
+	args := []string{"-f", wav.Name(), "--no-timestamps", "--no-prints"}
+	if t.modelPath != "" {
+		args = append(args, "-m", t.modelPath)
+	}
+	if t.language != "" {
+		args = append(args, "-l", t.language)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("whisper: %v: %s", err, truncateRunes(strings.TrimSpace(stderr.String()), maxTranscriptError))
+	}
+
+	// whisper.cpp prints one line per segment.
+	lines := strings.Fields(strings.ReplaceAll(stdout.String(), "\n", " "))
+	return strings.Join(lines, " "), nil
+}
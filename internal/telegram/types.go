@@ -1,18 +1,156 @@
 package telegram
 
 import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
 	"github.com/Shreehari-Acharya/vayuu/config"
 	"github.com/Shreehari-Acharya/vayuu/internal/agent"
 	"github.com/Shreehari-Acharya/vayuu/internal/tools"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
 	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
 )
 
 type ContentType string
 
 type Bot struct {
-	bot           *bot.Bot
-	agent         *agent.Agent
-	cfg           *config.Config
-	toolEnv       *tools.ToolEnv
-	currentChatID int64
+	bot     *bot.Bot
+	agent   *agent.Agent
+	cfg     *config.Config
+	toolEnv *tools.ToolEnv
+	users   *users.Registry
+
+	commands []command // Commands handled by the bot itself, in menu order
+	started  time.Time // When the bot was created, for /status
+
+	transcriber transcriber // Speech-to-text for voice notes; nil when not configured
+
+	userID   int64  // Telegram user ID of the bot itself
+	username string // Username of the bot, used to detect mentions in groups
+
+	queueMu sync.Mutex
+	queues  map[int64]*chatQueue // Pending messages of chats with work in progress
+	runs    map[int64]activeRun  // The in-flight agent run of each chat
+	slots   chan struct{}        // Limits how many chats are processed at once
+
+	approvalMu  sync.Mutex
+	approvals   map[string]*pendingApproval // Tool calls waiting for an Approve or Deny button, by approval ID
+	approvalSeq int
+}
+
+// pendingApproval is a tool call waiting for its Approve or Deny button to be pressed.
+type pendingApproval struct {
+	chatID      int64
+	requesterID int64               // The user whose run made the call; admins may answer too
+	adminOnly   bool                // Only admins may answer, not the requester
+	answer      chan approvalAnswer // Buffered, receives the first answer
+}
+
+// activeRun is an agent run in progress, which its requester or an admin can stop.
+type activeRun struct {
+	cancel      context.CancelFunc
+	requesterID int64 // The user the run is for
+}
+
+// approvalAnswer is the button pressed on an approval request.
+type approvalAnswer struct {
+	approved bool
+	by       string // Name of the user who pressed it
+}
+
+// command is a slash command handled by the bot rather than the agent.
+type command struct {
+	name        string // Including the leading slash
+	description string // Shown in Telegram's command menu
+	access      commandAccess
+	queued      bool // Runs on the chat's queue, after the messages before it
+	handle      func(ctx context.Context, req commandRequest)
+}
+
+// commandAccess tells who may use a command.
+type commandAccess int
+
+// commandRequest is one invocation of a command.
+type commandRequest struct {
+	msg        *models.Message
+	chatID     int64
+	user       users.User // Zero for unregistered senders
+	registered bool
+	arg        string // Text after the command
+}
+
+// incomingFile is a media file attached to a message, not yet downloaded.
+type incomingFile struct {
+	FileID   string
+	Kind     string // Shown to the agent, e.g. "photo" or "voice note"
+	Name     string // Original file name, if Telegram has one
+	MimeType string
+	Size     int64 // Reported size in bytes, zero if unknown
+	Duration int   // Length of audio in seconds, zero if unknown
+	Speech   bool  // Voice note or audio that can be transcribed
+}
+
+// incomingMedia is what the agent is told about the media attached to a message.
+type incomingMedia struct {
+	Notes       []string // Where each file was saved, or why it wasn't
+	Images      []string // Paths of saved images
+	Transcripts []string // Transcripts of voice notes and audio
+}
+
+// transcriber converts speech in an audio file to text.
+type transcriber interface {
+	Transcribe(ctx context.Context, path string) (string, error)
+}
+
+// httpTranscriber uses an OpenAI-compatible /audio/transcriptions endpoint, e.g. a local whisper.cpp server.
+type httpTranscriber struct {
+	url      string
+	apiKey   string
+	model    string
+	language string
+	client   *http.Client
+}
+
+// whisperTranscriber runs a local whisper.cpp binary, converting the audio with ffmpeg first.
+type whisperTranscriber struct {
+	binary    string
+	modelPath string
+	language  string
+}
+
+// savedFile is an incoming file saved in a workspace inbox.
+type savedFile struct {
+	Path     string
+	MimeType string
+	Size     int64
+}
+
+// renderedChunk is one message of a rendered reply.
+type renderedChunk struct {
+	HTML  string // Telegram HTML
+	Plain string // Source text of the chunk, sent as is if Telegram rejects the HTML
+}
+
+// chatQueue holds the messages of one chat waiting to be processed, in arrival order.
+type chatQueue struct {
+	pending []func(ctx context.Context)
+	running bool // A worker is draining the queue
+}
+
+// liveMessage is a Telegram message that is edited in place while the agent streams its response.
+type liveMessage struct {
+	tb        *Bot
+	chatID    int64
+	messageID int
+
+	mu     sync.Mutex
+	text   string // Response text streamed so far
+	status string // Progress line shown below the text, e.g. the running tool
+	shown  string // Last text successfully rendered into the message
+
+	stop chan struct{}
+	done chan struct{}
 }
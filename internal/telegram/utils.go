@@ -15,4 +15,14 @@ func validateFileForUpload(path string) error {
 		return fmt.Errorf("file too large (%.2f MB, max 50 MB)", float64(info.Size())/(1024*1024))
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// tailRunes returns the last limit runes of text, prefixed with an ellipsis when it had to be cut.
+func tailRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	prefix := []rune(liveTruncatePrefix)
+	return string(prefix) + string(runes[len(runes)-limit+len(prefix):])
+}
@@ -0,0 +1,124 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/go-telegram/bot"
+)
+
+// runWebhook serves the webhook and registers it with Telegram until ctx is done, then removes it again.
+// The server listens before the webhook is registered, so no update arrives while nothing is listening.
+func (tb *Bot) runWebhook(ctx context.Context) error {
+	webhookURL, err := url.Parse(tb.cfg.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("parse webhook URL: %w", err)
+	}
+	path := webhookURL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	secret := tb.cfg.WebhookSecretToken
+	if secret == "" {
+		if secret, err = randomSecret(); err != nil {
+			return err
+		}
+	}
+
+	addr := tb.cfg.WebhookListenAddr
+	if addr == "" {
+		addr = defaultWebhookListen
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen for webhook: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, tb.webhookHandler(secret))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: webhookReadTimeout, ReadTimeout: webhookReadTimeout}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if tb.cfg.WebhookCertFile != "" {
+			serveErr <- server.ServeTLS(listener, tb.cfg.WebhookCertFile, tb.cfg.WebhookKeyFile)
+		} else {
+			serveErr <- server.Serve(listener)
+		}
+	}()
+
+	if _, err := tb.bot.SetWebhook(ctx, &bot.SetWebhookParams{URL: tb.cfg.WebhookURL, SecretToken: secret}); err != nil {
+		server.Close()
+		return fmt.Errorf("set webhook: %w", err)
+	}
+	slog.Info("telegram webhook registered, listening for updates", "url", tb.cfg.WebhookURL, "addr", listener.Addr().String(), "tls", tb.cfg.WebhookCertFile != "")
+
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		tb.bot.StartWebhook(workersCtx)
+	}()
+
+	select {
+	case <-ctx.Done():
+		err = nil
+	case err = <-serveErr:
+		err = fmt.Errorf("webhook server: %w", err)
+	}
+
+	// The run's context is over, so cleanup gets its own deadline.
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookShutdownTimeout)
+	defer cancel()
+
+	if _, delErr := tb.bot.DeleteWebhook(cleanupCtx, &bot.DeleteWebhookParams{}); delErr != nil {
+		slog.Warn("failed to remove webhook", "error", delErr)
+	} else {
+		slog.Info("telegram webhook removed")
+	}
+	if shutErr := server.Shutdown(cleanupCtx); shutErr != nil && !errors.Is(shutErr, http.ErrServerClosed) {
+		slog.Warn("failed to stop webhook server", "error", shutErr)
+	}
+	stopWorkers()
+	<-workersDone
+
+	return err
+}
+
+// webhookHandler accepts update posts carrying the secret token and hands them to the bot's update workers.
+func (tb *Bot) webhookHandler(secret string) http.Handler {
+	updates := tb.bot.WebhookHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), []byte(secret)) != 1 {
+			slog.Warn("rejected webhook request with a wrong secret token", "remote_addr", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBodySize)
+		updates(w, r)
+	})
+}
+
+// randomSecret generates a secret token for a webhook registered without a configured one.
+func randomSecret() (string, error) {
+	b := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,164 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shreehari-Acharya/vayuu/config"
+	"github.com/Shreehari-Acharya/vayuu/internal/users"
+)
+
+// apiCall is a request the bot made to the fake Bot API.
+type apiCall struct {
+	method string
+	form   map[string]string
+}
+
+// fakeBotAPI is a local stand-in for the Telegram Bot API that records the calls made to it.
+func fakeBotAPI(t *testing.T) (*httptest.Server, <-chan apiCall) {
+	t.Helper()
+	calls := make(chan apiCall, 64)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := apiCall{method: path.Base(r.URL.Path), form: map[string]string{}}
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			for key, values := range r.MultipartForm.Value {
+				call.form[key] = values[0]
+			}
+		}
+
+		var result any = true
+		switch call.method {
+		case "getMe":
+			result = map[string]any{"id": 1, "is_bot": true, "first_name": "Vayuu", "username": "vayuu_bot"}
+		case "sendMessage":
+			result = map[string]any{"message_id": 1, "date": 0, "chat": map[string]any{"id": 42, "type": "private"}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+		calls <- call
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+// waitForCall returns the next call of method, skipping others, or fails the test after a few seconds.
+func waitForCall(t *testing.T, calls <-chan apiCall, method string) apiCall {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case call := <-calls:
+			if call.method == method {
+				return call
+			}
+		case <-timeout:
+			t.Fatalf("no %s call to the Bot API", method)
+			return apiCall{}
+		}
+	}
+}
+
+// freeAddr returns a loopback address with a port nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestWebhook(t *testing.T) {
+	api, calls := fakeBotAPI(t)
+
+	registry, err := users.Open(filepath.Join(t.TempDir(), "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Bootstrap(42, "owner"); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := freeAddr(t)
+	cfg := &config.Config{
+		TelegramToken:      "123:test",
+		TelegramAPIURL:     api.URL,
+		WebhookURL:         "http://" + addr + "/hook",
+		WebhookListenAddr:  addr,
+		WebhookSecretToken: "s3cret",
+	}
+	tb, err := NewBot(cfg, nil, nil, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tb.Start(ctx) }()
+
+	registered := waitForCall(t, calls, "setWebhook")
+	if registered.form["url"] != cfg.WebhookURL || registered.form["secret_token"] != cfg.WebhookSecretToken {
+		t.Fatalf("setWebhook got url %q and secret %q", registered.form["url"], registered.form["secret_token"])
+	}
+
+	post := func(secret string) int {
+		update := `{"update_id":1,"message":{"message_id":7,"date":0,"text":"/stop",` +
+			`"chat":{"id":42,"type":"private"},"from":{"id":42,"is_bot":false,"first_name":"Owner","username":"owner"},` +
+			`"entities":[{"type":"bot_command","offset":0,"length":5}]}}`
+		req, err := http.NewRequest(http.MethodPost, cfg.WebhookURL, strings.NewReader(update))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if secret != "" {
+			req.Header.Set(webhookSecretHeader, secret)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("wrong"); status != http.StatusUnauthorized {
+		t.Fatalf("update with a wrong secret token got status %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := post(""); status != http.StatusUnauthorized {
+		t.Fatalf("update without a secret token got status %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := post(cfg.WebhookSecretToken); status != http.StatusOK {
+		t.Fatalf("valid update got status %d, want %d", status, http.StatusOK)
+	}
+
+	// Only the valid update is handled: /stop with nothing running answers once.
+	reply := waitForCall(t, calls, "sendMessage")
+	if reply.form["chat_id"] != "42" || !strings.Contains(reply.form["text"], "Nothing is running") {
+		t.Fatalf("unexpected reply to chat %s: %q", reply.form["chat_id"], reply.form["text"])
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start returned %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("Start didn't return after the context ended")
+	}
+	waitForCall(t, calls, "deleteWebhook")
+	for len(calls) > 0 {
+		if call := <-calls; call.method == "sendMessage" {
+			t.Fatalf("unexpected second reply: %q", call.form["text"])
+		}
+	}
+}
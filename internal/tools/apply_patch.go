@@ -0,0 +1,416 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
+// applyPatch is a tool function that changes files by a unified diff or by a list of edit_file style replacements, all or nothing:
+// every change is worked out in memory first, and nothing is written unless all of them apply. Hunks whose context has drifted are
+// matched loosely, see applyHunks, and so are edits whose whitespace differs. The result is a diff of what changed, or would change with dry_run.
+func (e *ToolEnv) applyPatch(ctx context.Context, args applyPatchArgs) agent.ToolResult {
+	hasPatch := strings.TrimSpace(args.Patch) != ""
+	if hasPatch == (len(args.Edits) > 0) {
+		return agent.ErrorResult("give either patch or edits, not both or neither")
+	}
+	workDir, err := e.workDir(ctx)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	cs := &changeSet{env: e, workDir: workDir, files: make(map[string]*fileChange)}
+	var notes []string
+	if hasPatch {
+		notes, err = cs.applyDiff(ctx, args.Patch)
+	} else {
+		notes, err = cs.applyEdits(ctx, args.Edits)
+	}
+	if err != nil {
+		return agent.ErrorResult("%v; no files were changed", err)
+	}
+
+	changed := cs.changed()
+	if len(changed) == 0 {
+		return agent.TextResult("nothing to change: the files already look like that")
+	}
+	preview, added, removed := cs.preview(changed)
+	names := make([]string, len(changed))
+	for i, c := range changed {
+		names[i] = c.name
+	}
+
+	var summary string
+	if args.DryRun {
+		summary = fmt.Sprintf("dry run, nothing written: would change %d file(s), +%d -%d", len(changed), added, removed)
+	} else {
+		if err := commitChanges(changed); err != nil {
+			return agent.ErrorResult("%v", err)
+		}
+		summary = fmt.Sprintf("changed %d file(s), +%d -%d", len(changed), added, removed)
+	}
+	if len(notes) > 0 {
+		summary += "\n" + strings.Join(notes, "\n")
+	}
+	return agent.ToolResult{
+		Content:  summary + "\n\n" + preview,
+		Metadata: map[string]any{"files": names, "added": added, "removed": removed, "dry_run": args.DryRun},
+	}
+}
+
+// changeSet holds the new content of every file a call touches, in the order they were first touched.
+type changeSet struct {
+	env     *ToolEnv
+	workDir string
+	order   []*fileChange
+	files   map[string]*fileChange // By full path
+}
+
+// fileChange is a file before and after the changes so far.
+type fileChange struct {
+	path    string
+	name    string // As shown in the preview
+	mode    fs.FileMode
+	before  string
+	existed bool
+	after   string
+	absent  bool // The file doesn't exist after the changes so far
+	binary  bool // Can only be deleted; before still holds its bytes to restore it
+}
+
+// file returns the change of the file at a path given to the tool, reading the file the first time it is touched.
+func (cs *changeSet) file(ctx context.Context, path string) (*fileChange, error) {
+	full, err := cs.env.validatePath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := cs.files[full]; ok {
+		return c, nil
+	}
+
+	c := &fileChange{path: full, name: displayPath(cs.workDir, full), mode: 0644}
+	info, err := os.Stat(full)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		c.absent = true
+	case err != nil:
+		return nil, fmt.Errorf("accessing %s: %w", path, err)
+	case info.IsDir():
+		return nil, fmt.Errorf("%s is a directory", path)
+	case info.Size() > maxReadFileSize:
+		return nil, fmt.Errorf("%s is too large (%s, max %s)", path, formatBytes(info.Size()), formatBytes(maxReadFileSize))
+	default:
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		c.before, c.after, c.existed, c.mode = string(data), string(data), true, info.Mode().Perm()
+		c.binary = isBinary(data)
+	}
+	cs.files[full] = c
+	cs.order = append(cs.order, c)
+	return c, nil
+}
+
+// applyDiff applies a unified diff. Files can be changed, created from /dev/null, deleted to /dev/null or renamed.
+func (cs *changeSet) applyDiff(ctx context.Context, patch string) ([]string, error) {
+	files, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []string
+	for _, fp := range files {
+		if fp.newPath == "" {
+			c, err := cs.file(ctx, fp.oldPath)
+			if err != nil {
+				return nil, err
+			}
+			if c.absent {
+				return nil, fmt.Errorf("can't delete %s: it doesn't exist", fp.oldPath)
+			}
+			c.after, c.absent = "", true
+			continue
+		}
+
+		var lines []string
+		eol, final := "\n", true
+		var src *fileChange
+		if fp.oldPath != "" {
+			if src, err = cs.file(ctx, fp.oldPath); err != nil {
+				return nil, err
+			}
+			if src.absent {
+				return nil, fmt.Errorf("%s doesn't exist; diff against /dev/null to create it", fp.oldPath)
+			}
+			if src.binary {
+				return nil, fmt.Errorf("%s is a binary file", fp.oldPath)
+			}
+			lines, eol, final = splitLines(src.after)
+		}
+
+		updated, hunkNotes, err := applyHunks(fp.newPath, lines, fp.hunks)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, hunkNotes...)
+		// A hunk that reaches the end of the file decides whether it ends in a newline; otherwise the end is unchanged.
+		for _, h := range fp.hunks {
+			switch {
+			case h.noNewline:
+				final = false
+			case h.oldNoNewline:
+				final = true
+			}
+		}
+		text := joinLines(updated, eol, final)
+
+		dst := src
+		if fp.oldPath != fp.newPath {
+			if dst, err = cs.file(ctx, fp.newPath); err != nil {
+				return nil, err
+			}
+			switch {
+			case !dst.absent && src == nil:
+				return nil, fmt.Errorf("%s already exists; diff against it instead of /dev/null", fp.newPath)
+			case !dst.absent:
+				return nil, fmt.Errorf("can't rename %s to %s: it already exists", fp.oldPath, fp.newPath)
+			}
+			if src != nil {
+				dst.mode = src.mode
+				src.after, src.absent = "", true
+			}
+		}
+		dst.after, dst.absent = text, false
+	}
+	return notes, nil
+}
+
+// applyEdits applies a list of replacements in order. An edit with an empty old_string creates a file or fills an empty one.
+func (cs *changeSet) applyEdits(ctx context.Context, edits []patchEdit) ([]string, error) {
+	var notes []string
+	for i, edit := range edits {
+		c, err := cs.file(ctx, edit.Path)
+		if err != nil {
+			return nil, fmt.Errorf("edit %d: %w", i+1, err)
+		}
+		if c.binary && !c.absent {
+			return nil, fmt.Errorf("edit %d: %s is a binary file", i+1, edit.Path)
+		}
+
+		switch {
+		case c.absent:
+			if edit.OldString != "" {
+				return nil, fmt.Errorf("edit %d: %s doesn't exist; leave old_string empty to create it", i+1, edit.Path)
+			}
+			c.after, c.absent = edit.NewString, false
+		case edit.OldString == "":
+			if c.after != "" {
+				return nil, fmt.Errorf("edit %d: old_string is empty but %s already has content", i+1, edit.Path)
+			}
+			c.after = edit.NewString
+		default:
+			updated, note, err := replaceEdit(c.after, edit)
+			if err != nil {
+				return nil, fmt.Errorf("edit %d: %w", i+1, err)
+			}
+			if note != "" {
+				notes = append(notes, fmt.Sprintf("edit %d: %s", i+1, note))
+			}
+			c.after = updated
+		}
+	}
+	return notes, nil
+}
+
+// replaceEdit replaces old_string with new_string in content: the only occurrence, or every one with replace_all.
+// When old_string isn't there exactly, it is looked for line by line ignoring leading and trailing whitespace.
+func replaceEdit(content string, edit patchEdit) (string, string, error) {
+	switch n := strings.Count(content, edit.OldString); {
+	case n == 1 || n > 1 && edit.ReplaceAll:
+		return strings.ReplaceAll(content, edit.OldString, edit.NewString), "", nil
+	case n > 1:
+		return "", "", fmt.Errorf("old_string appears %d times in %s; add surrounding lines to make it unique, or set replace_all", n, edit.Path)
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	want := strings.Split(strings.TrimSuffix(strings.ReplaceAll(edit.OldString, "\r\n", "\n"), "\n"), "\n")
+	if strings.TrimSpace(edit.OldString) == "" {
+		return "", "", fmt.Errorf("old_string not found in %s", edit.Path)
+	}
+
+	var matches []int
+	for i := 0; i+len(want) <= len(lines); i++ {
+		found := true
+		for j, w := range want {
+			if strings.TrimSpace(lines[i+j]) != strings.TrimSpace(w) {
+				found = false
+				break
+			}
+		}
+		if found {
+			matches = append(matches, i)
+			i += len(want) - 1
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return "", "", fmt.Errorf("old_string not found in %s, even ignoring whitespace; check the current text with read_file", edit.Path)
+	case len(matches) > 1 && !edit.ReplaceAll:
+		return "", "", fmt.Errorf("old_string matches %d places in %s when ignoring whitespace; add surrounding lines to make it unique, or set replace_all", len(matches), edit.Path)
+	}
+
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+	}
+	replacement := strings.ReplaceAll(strings.ReplaceAll(edit.NewString, "\r\n", "\n"), "\n", eol)
+
+	// Replace from the last match back, so the earlier line indexes stay valid.
+	for m := len(matches) - 1; m >= 0; m-- {
+		at := matches[m]
+		start := len(strings.Join(lines[:at], ""))
+		span := strings.Join(lines[at:at+len(want)], "")
+		text := replacement
+		if text != "" && strings.HasSuffix(span, "\n") && !strings.HasSuffix(text, "\n") {
+			text += eol
+		}
+		content = content[:start] + text + content[start+len(span):]
+	}
+
+	lineNos := make([]string, len(matches))
+	for i, at := range matches {
+		lineNos[i] = fmt.Sprint(at + 1)
+	}
+	return content, fmt.Sprintf("matched %s at line %s ignoring whitespace", edit.Path, strings.Join(lineNos, ", ")), nil
+}
+
+// changed returns the files whose content or existence the changes alter.
+func (cs *changeSet) changed() []*fileChange {
+	var changed []*fileChange
+	for _, c := range cs.order {
+		if c.existed == c.absent || c.after != c.before {
+			changed = append(changed, c)
+		}
+	}
+	return changed
+}
+
+// preview returns a unified diff of the changes, cut at maxPreviewBytes, and the number of lines added and removed.
+func (cs *changeSet) preview(changed []*fileChange) (string, int, int) {
+	var sb strings.Builder
+	added, removed := 0, 0
+	for _, c := range changed {
+		if c.binary && c.absent {
+			fmt.Fprintf(&sb, "deleted %s (binary)\n", c.name)
+			continue
+		}
+		var before, after []string
+		if c.existed {
+			before, _, _ = splitLines(c.before)
+		}
+		if !c.absent {
+			after, _, _ = splitLines(c.after)
+		}
+		diff, a, r := unifiedDiff(c.name, diffLines(before, after))
+		added, removed = added+a, removed+r
+
+		switch {
+		case !c.existed:
+			fmt.Fprintf(&sb, "new file %s\n", c.name)
+		case c.absent:
+			fmt.Fprintf(&sb, "deleted %s\n", c.name)
+		}
+		sb.WriteString(diff)
+	}
+
+	preview := strings.TrimSuffix(sb.String(), "\n")
+	if len(preview) > maxPreviewBytes {
+		cut := strings.LastIndexByte(preview[:maxPreviewBytes], '\n')
+		preview = preview[:max(cut, 0)] + "\n… (preview truncated)"
+	}
+	return preview, added, removed
+}
+
+// commitChanges writes the changed files, each through a temporary file renamed into place. If one fails, the files already written are put back.
+func commitChanges(changed []*fileChange) error {
+	var done []*fileChange
+	for _, c := range changed {
+		if err := c.write(c.after, c.absent); err != nil {
+			for i := len(done) - 1; i >= 0; i-- {
+				if err := done[i].write(done[i].before, !done[i].existed); err != nil {
+					slog.Error("failed to restore file after a failed patch", "path", done[i].path, "error", err)
+				}
+			}
+			return fmt.Errorf("writing %s: %v; no files were changed", c.name, err)
+		}
+		done = append(done, c)
+	}
+	return nil
+}
+
+// write makes the file hold content, or removes it when absent.
+func (c *fileChange) write(content string, absent bool) error {
+	if absent {
+		if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(content)
+	if err == nil {
+		err = tmp.Chmod(c.mode)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// splitLines splits text into lines, returning its line ending, \r\n when it has any, and whether it ends with one. Empty text has no lines.
+func splitLines(text string) ([]string, string, bool) {
+	eol := "\n"
+	if strings.Contains(text, "\r\n") {
+		eol = "\r\n"
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	if text == "" {
+		return nil, eol, true
+	}
+	final := strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), eol, final
+}
+
+// joinLines joins lines with a line ending, adding one after the last line if final is set.
+func joinLines(lines []string, eol string, final bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	text := strings.Join(lines, eol)
+	if final {
+		text += eol
+	}
+	return text
+}
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"sync"
 
 	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+	"github.com/Shreehari-Acharya/vayuu/internal/sandbox"
 )
 
-// ToolEnv provides a shared environment for tools, allowing them to access common resources such as the work directory and a file sender function for sending content back to the user.
-func NewToolEnv(workDir string) (*ToolEnv, error) {
+// ToolEnv provides a shared environment for tools, allowing them to access common resources such as the work directory and the sandbox shell commands run in.
+func NewToolEnv(workDir string, sb *sandbox.Sandbox) (*ToolEnv, error) {
 	if workDir == "" {
 		return nil, fmt.Errorf("work directory must not be empty")
 	}
@@ -20,28 +22,30 @@ func NewToolEnv(workDir string) (*ToolEnv, error) {
 	if !info.IsDir() {
 		return nil, fmt.Errorf("work directory is not a directory: %s", workDir)
 	}
-	return &ToolEnv{WorkDir: workDir}, nil
+	return &ToolEnv{WorkDir: workDir, Sandbox: sb, jobs: make(map[string]*job), sessions: make(map[string]*shellSession)}, nil
 }
 
-// SetFileSender sets the FileSender function in the ToolEnv, allowing tools to send files back to the user through the Telegram bot. This method is thread-safe, ensuring that concurrent access to the FileSender is properly synchronized.
-func (e *ToolEnv) SetFileSender(sender FileSenderFunc) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	e.FileSender = sender
-}
+// Close stops everything the tools left running in the background: it kills the running jobs and closes the shell sessions.
+// Call it when Vayuu shuts down, after the channels have stopped.
+func (e *ToolEnv) Close() {
+	e.killAllJobs()
 
-// SetCurrentChatID updates the current chat ID in both the Bot struct and the tool environment, ensuring that tools have access to the correct chat context when sending messages or files. This method is thread-safe, allowing concurrent updates to the current
-func (e *ToolEnv) SetCurrentChatID(chatID int64) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	e.CurrentChatID = chatID
-}
+	e.sessionsMu.Lock()
+	sessions := make([]*shellSession, 0, len(e.sessions))
+	for _, s := range e.sessions {
+		sessions = append(sessions, s)
+	}
+	e.sessionsMu.Unlock()
 
-// getFileSender retrieves the current FileSender function from the ToolEnv in a thread-safe manner, allowing tools to access the function for sending files back to the user through the Telegram bot.
-func (e *ToolEnv) getFileSender() FileSenderFunc {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-	return e.FileSender
+	var wg sync.WaitGroup
+	for _, s := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.closeSession(s)
+		}()
+	}
+	wg.Wait()
 }
 
 // RegisterAll registers all available tools in the provided ToolEnv with the given Agent instance. It iterates through the tool definitions, creates Tool instances, and registers them with the agent, logging the registration process and returning any errors encountered during registration.
@@ -52,6 +56,7 @@ func RegisterAll(env *ToolEnv, a *agent.Agent) error {
 			Description: def.description,
 			Parameters:  def.parameters,
 			Handler:     def.handler,
+			Access:      def.access,
 		}
 		if err := a.RegisterTool(tool); err != nil {
 			return fmt.Errorf("register tool %q: %w", def.name, err)
@@ -4,7 +4,89 @@ import "time"
 
 const (
 	maxCommandTimeout = 30 * time.Second
+	commandWaitDelay  = 2 * time.Second
 	maxCommands       = 20
 	maxReadFileSize   = 5 * 1024 * 1024
 	maxCommandOutput  = 10 * 1024 * 1024
+	defaultReadLines  = 2000 // Lines read_file returns from an offset when no limit is given
 )
+
+const (
+	patchFuzz        = 2    // Context lines at each end of a hunk that may be dropped to make it match
+	diffContextLines = 3    // Unchanged lines around each change in a diff preview
+	maxDiffEdits     = 1000 // Changes a preview diff works out line by line before showing the rest as replaced
+	maxPreviewBytes  = 16 * 1024
+)
+
+const (
+	maxJobDuration     = 24 * time.Hour
+	maxRunningJobs     = 8
+	jobLogDir          = "jobs" // Under the workspace of the run that started the job
+	jobIDBytes         = 4
+	defaultOutputLines = 50
+	maxOutputLines     = 500
+	maxOutputTailBytes = 64 * 1024
+	jobStatusLines     = 10 // Lines of output shown by job_status and in notifications
+	jobNotifyTimeout   = 30 * time.Second
+)
+
+const (
+	jobRunning  jobState = "running"
+	jobExited   jobState = "exited"    // Ended on its own; see the exit code
+	jobKilled   jobState = "killed"    // Stopped by kill_job
+	jobTimedOut jobState = "timed out" // Killed after maxJobDuration
+	jobFailed   jobState = "failed"    // Couldn't be waited for
+)
+
+const (
+	maxShellSessions   = 16
+	sessionIdleTimeout = 30 * time.Minute // Sessions unused for this long are closed
+	sessionRows        = 50
+	sessionCols        = 200
+	shellPrompt        = "[vayuu]$ "
+	maxSessionBuffer   = 1024 * 1024 // Unread output kept per session; older output is dropped beyond it
+	maxSessionRead     = 32 * 1024   // Output returned by one call; the rest waits for shell_read
+	sessionQuietPeriod = time.Second // Output is complete once the shell has been silent this long
+	defaultSessionWait = 15 * time.Second
+	maxSessionWait     = 120 * time.Second
+	sessionOpenWait    = 10 * time.Second
+)
+
+const (
+	defaultPageSize    = 200 // Entries per page of list_dir and glob
+	maxPageSize        = 1000
+	defaultGrepMatches = 100
+	maxGrepMatches     = 500
+	defaultListDepth   = 1
+	maxListDepth       = 10
+	maxGrepContext     = 10
+	maxGrepLineLength  = 300     // Longer lines are cut in grep results
+	maxWalkEntries     = 100_000 // Entries visited before a walk gives up
+	binarySniffBytes   = 8000    // Leading bytes checked for NUL to tell binary files from text
+)
+
+// fileTypes maps the file types grep filters by to their extensions.
+var fileTypes = map[string][]string{
+	"c":      {".c", ".h"},
+	"cpp":    {".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx", ".h"},
+	"css":    {".css", ".scss", ".sass", ".less"},
+	"go":     {".go"},
+	"html":   {".html", ".htm"},
+	"java":   {".java"},
+	"js":     {".js", ".jsx", ".mjs", ".cjs"},
+	"json":   {".json"},
+	"kotlin": {".kt", ".kts"},
+	"md":     {".md", ".markdown"},
+	"php":    {".php"},
+	"py":     {".py", ".pyi"},
+	"rb":     {".rb"},
+	"rust":   {".rs"},
+	"sh":     {".sh", ".bash", ".zsh"},
+	"sql":    {".sql"},
+	"swift":  {".swift"},
+	"toml":   {".toml"},
+	"ts":     {".ts", ".tsx", ".mts", ".cts"},
+	"txt":    {".txt"},
+	"xml":    {".xml"},
+	"yaml":   {".yaml", ".yml"},
+}
@@ -1,49 +1,44 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
 )
 
 // editFile is a tool function that edits a file by replacing the first occurrence of a specified old string with a new string. It validates the file path, reads the file content, performs the replacement, and writes the updated content back to the file. The function returns a summary of the edit operation, including the number of lines replaced and the change in file size.
-func (e *ToolEnv) editFile(args map[string]any) string {
-	path, okPath := args["path"].(string)
-	oldStr, okOld := args["old_string"].(string)
-	newStr, okNew := args["new_string"].(string)
-
-	if !okPath || !okOld || !okNew {
-		return "error: 'path', 'old_string', and 'new_string' must be strings"
-	}
-
-	fullPath, err := e.validatePath(path)
+func (e *ToolEnv) editFile(ctx context.Context, args editFileArgs) agent.ToolResult {
+	fullPath, err := e.validatePath(ctx, args.Path)
 	if err != nil {
-		return fmt.Sprintf("error: %v", err)
+		return agent.ErrorResult("%v", err)
 	}
 
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
-		return fmt.Sprintf("error reading file: %v", err)
+		return agent.ErrorResult("reading file: %v", err)
 	}
 
 	original := string(content)
 
-	if !strings.Contains(original, oldStr) {
-		return "error: old_string not found in file — check exact whitespace and line breaks"
+	if !strings.Contains(original, args.OldString) {
+		return agent.ErrorResult("old_string not found in file — check exact whitespace and line breaks")
 	}
 
-	if n := strings.Count(original, oldStr); n > 1 {
-		return fmt.Sprintf("error: old_string appears %d times — provide a more specific match", n)
+	if n := strings.Count(original, args.OldString); n > 1 {
+		return agent.ErrorResult("old_string appears %d times — provide a more specific match", n)
 	}
 
-	updated := strings.Replace(original, oldStr, newStr, 1)
+	updated := strings.Replace(original, args.OldString, args.NewString, 1)
 
 	if err := os.WriteFile(fullPath, []byte(updated), 0644); err != nil {
-		return fmt.Sprintf("error writing file: %v", err)
+		return agent.ErrorResult("writing file: %v", err)
 	}
 
-	oldLines := strings.Count(oldStr, "\n") + 1
-	newLines := strings.Count(newStr, "\n") + 1
+	oldLines := strings.Count(args.OldString, "\n") + 1
+	newLines := strings.Count(args.NewString, "\n") + 1
 	diff := len(updated) - len(original)
 
 	var delta string
@@ -56,5 +51,8 @@ func (e *ToolEnv) editFile(args map[string]any) string {
 		delta = "no size change"
 	}
 
-	return fmt.Sprintf("edited %s: replaced %d line(s) with %d line(s) (%s)", path, oldLines, newLines, delta)
+	return agent.ToolResult{
+		Content:  fmt.Sprintf("edited %s: replaced %d line(s) with %d line(s) (%s)", args.Path, oldLines, newLines, delta),
+		Metadata: map[string]any{"path": fullPath, "size_delta": diff},
+	}
 }
@@ -2,83 +2,94 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os/exec"
 	"strings"
-)
-
-// executeCommand is a tool function that executes a shell command or an array of shell commands. It validates the input, runs the command(s) in the specified working directory, and returns the output or any errors encountered during execution. The function also handles command timeouts and limits the size of the output to prevent excessive data from being returned.
-func (e *ToolEnv) executeCommand(args map[string]any) string {
-	if cmdStr, ok := args["command"].(string); ok {
-		return e.runCommand(cmdStr)
-	}
-
-	if cmdArray, ok := args["command"].([]any); ok {
-		return e.runMultipleCommands(cmdArray)
-	}
 
-	return "error: command must be a string or array of strings"
-}
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
 
-// runMultipleCommands executes multiple shell commands sequentially, collecting their outputs and errors. It validates each command, runs them in the specified working directory, and returns a combined result that includes the output of each command or any errors encountered.
-func (e *ToolEnv) runMultipleCommands(cmds []any) string {
-	if len(cmds) == 0 {
-		return "error: command array is empty"
-	}
-	if len(cmds) > maxCommands {
-		return fmt.Sprintf("error: too many commands (max %d)", maxCommands)
+// executeCommand is a tool function that executes a shell command or an array of shell commands. It validates the input, runs the command(s) in the specified working directory, and returns the output or any errors encountered during execution. Commands stop when the run is cancelled; the function also handles command timeouts and limits the size of the output to prevent excessive data from being returned.
+func (e *ToolEnv) executeCommand(ctx context.Context, args executeCommandArgs) agent.ToolResult {
+	switch {
+	case len(args.Command) == 0:
+		return agent.ErrorResult("command array is empty")
+	case len(args.Command) > maxCommands:
+		return agent.ErrorResult("too many commands (max %d)", maxCommands)
+	case len(args.Command) == 1:
+		output, err := e.runCommand(ctx, args.Command[0])
+		if err != nil {
+			return agent.ErrorResult("%v", err)
+		}
+		return agent.TextResult(output)
 	}
 
 	var results []string
-	for i, cmd := range cmds {
-		cmdStr, ok := cmd.(string)
-		if !ok {
-			return fmt.Sprintf("error: command[%d] must be a string", i)
+	failed := 0
+	for i, cmd := range args.Command {
+		if strings.TrimSpace(cmd) == "" {
+			return agent.ErrorResult("command[%d] is empty", i)
 		}
-		if strings.TrimSpace(cmdStr) == "" {
-			return fmt.Sprintf("error: command[%d] is empty", i)
+		if ctx.Err() != nil {
+			results = append(results, fmt.Sprintf("command %d skipped: run cancelled", i+1))
+			failed++
+			continue
 		}
 
-		result := e.runCommand(cmdStr)
-		if strings.HasPrefix(result, "error:") {
-			results = append(results, fmt.Sprintf("command %d failed: %s\n%s", i+1, cmdStr, result))
+		output, err := e.runCommand(ctx, cmd)
+		if err != nil {
+			failed++
+			results = append(results, fmt.Sprintf("command %d failed: %s\nerror: %v", i+1, cmd, err))
 		} else {
-			results = append(results, fmt.Sprintf("=== command %d: %s ===\n%s", i+1, cmdStr, result))
+			results = append(results, fmt.Sprintf("=== command %d: %s ===\n%s", i+1, cmd, output))
 		}
 	}
-	return strings.Join(results, "\n\n")
+
+	return agent.ToolResult{
+		Content:  strings.Join(results, "\n\n"),
+		IsError:  failed == len(args.Command),
+		Metadata: map[string]any{"commands": len(args.Command), "failed": failed},
+	}
 }
 
-// runCommand executes a single shell command with a timeout and output size limit, returning the command's output or any errors encountered during execution.
-func (e *ToolEnv) runCommand(cmd string) string {
+// runCommand executes a single shell command with a timeout and output size limit, returning the command's output or any errors encountered during execution. The command is killed when ctx is cancelled.
+func (e *ToolEnv) runCommand(ctx context.Context, cmd string) (string, error) {
 	if strings.TrimSpace(cmd) == "" {
-		return "error: command is empty"
+		return "", fmt.Errorf("command is empty")
 	}
 
-	slog.Debug("executing command", "dir", e.WorkDir, "cmd", cmd)
+	workDir, err := e.workDir(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	slog.Debug("executing command", "dir", workDir, "cmd", cmd)
 
-	ctx, cancel := context.WithTimeout(context.Background(), maxCommandTimeout)
+	cmdCtx, cancel := context.WithTimeout(ctx, maxCommandTimeout)
 	defer cancel()
 
-	proc := exec.CommandContext(ctx, "bash", "-c", cmd)
-	proc.Dir = e.WorkDir
+	proc := e.Sandbox.Command(cmdCtx, workDir, cmd)
+	killProcessGroup(proc)
 
 	output, err := proc.CombinedOutput()
 
-	if ctx.Err() == context.DeadlineExceeded {
-		return fmt.Sprintf("error: command timed out after %v", maxCommandTimeout)
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("command cancelled: %w", ctx.Err())
+	}
+	if errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("command timed out after %v", maxCommandTimeout)
 	}
 
 	if len(output) > maxCommandOutput {
-		return fmt.Sprintf("error: output too large (%s, max %s)\nfirst 1000 chars:\n%s",
+		return "", fmt.Errorf("output too large (%s, max %s)\nfirst 1000 chars:\n%s",
 			formatBytes(int64(len(output))), formatBytes(int64(maxCommandOutput)),
 			string(output[:1000]))
 	}
 
 	if err != nil {
-		return fmt.Sprintf("error: %v\noutput: %s", err, string(output))
+		return "", fmt.Errorf("%v\noutput: %s", err, string(output))
 	}
 
-	return string(output)
+	return string(output), nil
 }
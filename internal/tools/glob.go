@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
+// glob is a tool function that finds the files and directories under a path whose relative path matches a doublestar pattern, such as **/*.go or src/{api,web}/**/*.ts.
+// Entries ignored by .gitignore are left out unless include_ignored is set, and results come a page at a time.
+func (e *ToolEnv) glob(ctx context.Context, args globArgs) agent.ToolResult {
+	pattern := strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(args.Pattern)), "./")
+	if pattern == "" {
+		return agent.ErrorResult("pattern must not be empty")
+	}
+	if path.IsAbs(pattern) || pattern == ".." || strings.HasPrefix(pattern, "../") || strings.Contains(pattern, "/../") {
+		return agent.ErrorResult("pattern must be relative to path; set path to search elsewhere")
+	}
+	if err := validateGlob(pattern); err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	base, workDir, err := e.resolveDir(ctx, args.Path)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	if !isDirectory(base) {
+		return agent.ErrorResult("path is not a directory")
+	}
+
+	// Walk only the part of the tree the literal leading segments of the pattern point to.
+	prefix, rest := globBase(pattern)
+	root := filepath.Join(base, filepath.FromSlash(prefix))
+	if !isDirectory(root) {
+		return agent.TextResult("no matches")
+	}
+
+	p := newPage(args.Offset, args.Limit, defaultPageSize, maxPageSize)
+	w := &treeWalker{all: args.IncludeIgnored, maxDepth: globDepth(rest)}
+	var results []string
+	err = w.walk(workDir, root, func(full string, d fs.DirEntry, depth int) error {
+		rel, _ := filepath.Rel(root, full)
+		if !matchGlob(rest, filepath.ToSlash(rel)) || !p.next() {
+			if p.more {
+				return errWalkDone
+			}
+			return nil
+		}
+		entry := displayPath(workDir, full)
+		if d.IsDir() {
+			entry += "/"
+		}
+		results = append(results, entry)
+		return nil
+	})
+	if err != nil {
+		return agent.ErrorResult("walking %s: %v", args.Path, err)
+	}
+
+	if len(results) == 0 {
+		if p.offset > 0 && p.seen > 0 {
+			return agent.TextResult(fmt.Sprintf("no matches past offset %d (%d in total)", p.offset, p.seen))
+		}
+		return agent.TextResult("no matches" + p.footer("matches", w))
+	}
+	return agent.ToolResult{
+		Content:  strings.Join(results, "\n") + p.footer("matches", w),
+		Metadata: map[string]any{"matches": len(results), "more": p.more},
+	}
+}
+
+// globBase splits a pattern into its leading segments without wildcards, which name a directory to start from, and the rest.
+func globBase(pattern string) (string, string) {
+	segments := strings.Split(pattern, "/")
+	n := 0
+	for n < len(segments)-1 && !strings.ContainsAny(segments[n], `*?[{\`) {
+		n++
+	}
+	return strings.Join(segments[:n], "/"), strings.Join(segments[n:], "/")
+}
+
+// globDepth returns how deep a walk has to go for a pattern to match, or zero when ** lets it match at any depth.
+func globDepth(pattern string) int {
+	depth := 0
+	for _, p := range expandBraces(pattern) {
+		if strings.Contains(p, "**") {
+			return 0
+		}
+		depth = max(depth, strings.Count(p, "/")+1)
+	}
+	return depth
+}
+
+// matchGlob reports whether a slash-separated path matches a pattern. *, ? and [...] match within one segment as in path.Match,
+// a ** segment matches any number of segments, including none, and {a,b} matches either alternative.
+func matchGlob(pattern, name string) bool {
+	names := strings.Split(name, "/")
+	for _, p := range expandBraces(pattern) {
+		if matchSegments(strings.Split(p, "/"), names) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for len(pattern) > 1 && pattern[1] == "**" {
+				pattern = pattern[1:]
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// expandBraces expands the {a,b} alternatives of a pattern into the patterns they stand for. Braces may nest.
+func expandBraces(pattern string) []string {
+	open, depth := -1, 0
+	var commas []int
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			if depth--; depth > 0 {
+				continue
+			}
+			var expanded []string
+			start := open + 1
+			for _, end := range append(commas, i) {
+				alternative := pattern[:open] + pattern[start:end] + pattern[i+1:]
+				expanded = append(expanded, expandBraces(alternative)...)
+				start = end + 1
+			}
+			return expanded
+		}
+	}
+	return []string{pattern}
+}
+
+// validateGlob checks that a pattern is well formed, so a bad pattern is reported instead of silently matching nothing.
+func validateGlob(pattern string) error {
+	for _, p := range expandBraces(pattern) {
+		for _, segment := range strings.Split(p, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
+// grep is a tool function that searches the text files under a path, or a single file, for lines matching a regular expression.
+// Files can be narrowed by a glob or a file type, matches can come with lines of context, and results come a page at a time.
+// Binary files, files over maxReadFileSize and, unless include_ignored is set, files ignored by .gitignore are skipped.
+func (e *ToolEnv) grep(ctx context.Context, args grepArgs) agent.ToolResult {
+	if args.Pattern == "" {
+		return agent.ErrorResult("pattern must not be empty")
+	}
+	expr := args.Pattern
+	if args.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return agent.ErrorResult("invalid pattern: %v", err)
+	}
+
+	var exts []string
+	if args.Type != "" {
+		var ok bool
+		if exts, ok = fileTypes[strings.ToLower(args.Type)]; !ok {
+			types := make([]string, 0, len(fileTypes))
+			for t := range fileTypes {
+				types = append(types, t)
+			}
+			slices.Sort(types)
+			return agent.ErrorResult("unknown file type %q; use one of %s", args.Type, strings.Join(types, ", "))
+		}
+	}
+	include := strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(args.Glob)), "./")
+	if include != "" {
+		if err := validateGlob(include); err != nil {
+			return agent.ErrorResult("%v", err)
+		}
+	}
+
+	target, workDir, err := e.resolveDir(ctx, args.Path)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	s := &grepSearch{
+		re:      re,
+		context: min(max(args.Context, 0), maxGrepContext),
+		page:    newPage(args.Offset, args.Limit, defaultGrepMatches, maxGrepMatches),
+		workDir: workDir,
+	}
+	var w *treeWalker
+	if !isDirectory(target) {
+		s.searchFile(target)
+	} else {
+		w = &treeWalker{all: args.IncludeIgnored}
+		err = w.walk(workDir, target, func(full string, d fs.DirEntry, _ int) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if len(exts) > 0 && !slices.Contains(exts, strings.ToLower(filepath.Ext(full))) {
+				return nil
+			}
+			if include != "" {
+				rel, _ := filepath.Rel(target, full)
+				name := filepath.ToSlash(rel)
+				if !strings.Contains(include, "/") {
+					name = path.Base(name)
+				}
+				if !matchGlob(include, name) {
+					return nil
+				}
+			}
+			if s.searchFile(full); s.page.more {
+				return errWalkDone
+			}
+			return nil
+		})
+		if err != nil {
+			return agent.ErrorResult("searching %s: %v", displayPath(workDir, target), err)
+		}
+	}
+
+	p := s.page
+	if p.shown == 0 {
+		if p.offset > 0 && p.seen > 0 {
+			return agent.TextResult(fmt.Sprintf("no matches past offset %d (%d in total)", p.offset, p.seen))
+		}
+		return agent.TextResult("no matches" + p.footer("matches", w))
+	}
+	return agent.ToolResult{
+		Content:  strings.TrimSuffix(s.out.String(), "\n") + p.footer("matches", w),
+		Metadata: map[string]any{"matches": p.shown, "files": s.files, "more": p.more},
+	}
+}
+
+// grepSearch collects the matches of a grep call across files.
+type grepSearch struct {
+	re      *regexp.Regexp
+	context int
+	page    *page
+	workDir string
+
+	out   strings.Builder
+	files int // Files with matches on the page
+}
+
+// searchFile adds the matches of one file that fall on the page, with their context, in the style of grep:
+// path:line:text for matching lines, path-line-text for context and -- between groups that aren't adjacent.
+func (s *grepSearch) searchFile(full string) {
+	info, err := os.Stat(full)
+	if err != nil || info.Size() > maxReadFileSize {
+		return
+	}
+	data, err := os.ReadFile(full)
+	if err != nil || isBinary(data) {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	var hits []int
+	for i, line := range lines {
+		if !s.re.MatchString(line) {
+			continue
+		}
+		if s.page.next() {
+			hits = append(hits, i)
+		} else if s.page.more {
+			break
+		}
+	}
+	if len(hits) == 0 {
+		return
+	}
+
+	name := displayPath(s.workDir, full)
+	if s.files > 0 && s.context > 0 {
+		s.out.WriteString("--\n")
+	}
+	s.files++
+
+	shown := -1 // Last line written
+	for n, hit := range hits {
+		from, to := max(hit-s.context, shown+1), min(hit+s.context, len(lines)-1)
+		if shown >= 0 && from > shown+1 {
+			s.out.WriteString("--\n")
+		}
+		for i := from; i <= to; i++ {
+			// Context after this hit stops short of the next one, which writes its own context.
+			if n+1 < len(hits) && i >= hits[n+1] {
+				break
+			}
+			sep := "-"
+			if slices.Contains(hits, i) {
+				sep = ":"
+			}
+			fmt.Fprintf(&s.out, "%s%s%d%s%s\n", name, sep, i+1, sep, truncateLine(strings.TrimSuffix(lines[i], "\r")))
+			shown = i
+		}
+	}
+}
+
+// truncateLine cuts a line longer than maxGrepLineLength bytes, on a character boundary.
+func truncateLine(line string) string {
+	if len(line) <= maxGrepLineLength {
+		return line
+	}
+	n := maxGrepLineLength
+	for n > 0 && !utf8.RuneStart(line[n]) {
+		n--
+	}
+	return line[:n] + " …"
+}
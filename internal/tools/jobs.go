@@ -0,0 +1,389 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
+// startJob is a tool function that starts a shell command in the background and returns its job ID right away. The command runs in the workspace like execute_command, but without its timeout, and its output goes to a log under jobs/ in the workspace. When the job ends the chat is told, and the agent can be woken to summarize the result.
+func (e *ToolEnv) startJob(ctx context.Context, args startJobArgs) agent.ToolResult {
+	if strings.TrimSpace(args.Command) == "" {
+		return agent.ErrorResult("command is empty")
+	}
+
+	workDir, err := e.workDir(ctx)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	if running := e.runningJobs(); running >= maxRunningJobs {
+		return agent.ErrorResult("%d jobs are already running (max %d); wait for one to end or kill one", running, maxRunningJobs)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	logPath := filepath.Join(workDir, jobLogDir, id+".log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return agent.ErrorResult("create job log directory: %v", err)
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return agent.ErrorResult("create job log: %v", err)
+	}
+
+	// The job outlives the run that started it, so it only keeps the run's values, not its cancellation.
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), maxJobDuration)
+	proc := e.Sandbox.Command(jobCtx, workDir, args.Command)
+	proc.Stdout = logFile
+	proc.Stderr = logFile
+	killProcessGroup(proc)
+
+	if err := proc.Start(); err != nil {
+		cancel()
+		logFile.Close()
+		return agent.ErrorResult("start job: %v", err)
+	}
+
+	j := &job{
+		id:      id,
+		command: args.Command,
+		workDir: workDir,
+		logPath: logPath,
+		started: time.Now(),
+		wake:    args.WakeAgent,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		state:   jobRunning,
+	}
+	j.origin, j.hasOrigin = agent.OriginFrom(ctx)
+
+	e.jobsMu.Lock()
+	e.jobs[id] = j
+	e.jobsMu.Unlock()
+
+	slog.Info("job started", "id", id, "dir", workDir, "cmd", args.Command)
+	go e.waitJob(jobCtx, j, proc, logFile)
+
+	rel, _ := filepath.Rel(workDir, logPath)
+	return agent.ToolResult{
+		Content:  fmt.Sprintf("started job %s, logging to %s. Follow it with job_status or job_output; the chat is told when it ends.", id, rel),
+		Metadata: map[string]any{"job_id": id},
+	}
+}
+
+// waitJob waits for a job to end, records how it ended and tells the chat that started it.
+func (e *ToolEnv) waitJob(jobCtx context.Context, j *job, proc *exec.Cmd, logFile *os.File) {
+	err := proc.Wait()
+	logFile.Close()
+
+	j.mu.Lock()
+	j.ended = time.Now()
+	var exitErr *exec.ExitError
+	switch {
+	case j.killed:
+		j.state = jobKilled
+	case errors.Is(jobCtx.Err(), context.DeadlineExceeded):
+		j.state = jobTimedOut
+	case err == nil:
+		j.state = jobExited
+	case errors.As(err, &exitErr):
+		j.state = jobExited
+		j.exitCode = exitErr.ExitCode()
+	default:
+		j.state = jobFailed
+		slog.Warn("failed to wait for job", "id", j.id, "error", err)
+	}
+	killed := j.killed
+	slog.Info("job ended", "id", j.id, "state", j.state, "exit_code", j.exitCode)
+	j.mu.Unlock()
+
+	j.cancel()
+	close(j.done)
+
+	// Whoever killed the job already knows.
+	if killed || !j.hasOrigin || e.Notifier == nil {
+		return
+	}
+	e.notifyJob(context.WithoutCancel(jobCtx), j)
+}
+
+// notifyJob tells the chat that started a job how it ended, and wakes the agent to summarize it if asked to.
+func (e *ToolEnv) notifyJob(ctx context.Context, j *job) {
+	tail, err := tailLines(j.logPath, jobStatusLines)
+	if err != nil {
+		slog.Warn("failed to read job log", "id", j.id, "error", err)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, jobNotifyTimeout)
+	defer cancel()
+
+	text := fmt.Sprintf("%s Job `%s` %s: `%s`", jobIcon(j), j.id, j.summary(), j.command)
+	if tail != "" {
+		text += "\n\n```\n" + tail + "\n```"
+	}
+	if err := e.Notifier.Notify(sendCtx, j.origin, text); err != nil {
+		slog.Warn("failed to notify about job", "id", j.id, "error", err)
+	}
+
+	if !j.wake {
+		return
+	}
+	rel, _ := filepath.Rel(j.workDir, j.logPath)
+	prompt := fmt.Sprintf("[Background job %s started with `%s` %s. Its output is in %s; the last lines are:\n%s\n]\n"+
+		"Summarize the result for the user, reading more of the log if needed.", j.id, j.command, j.summary(), rel, tail)
+	if err := e.Notifier.Wake(ctx, j.origin, prompt); err != nil {
+		slog.Warn("failed to wake the agent about job", "id", j.id, "error", err)
+	}
+}
+
+// jobStatus is a tool function that reports on a background job, with the last lines of its output, or lists the jobs of the workspace when no ID is given.
+func (e *ToolEnv) jobStatus(ctx context.Context, args jobStatusArgs) agent.ToolResult {
+	if strings.TrimSpace(args.ID) == "" {
+		return e.listJobs(ctx)
+	}
+
+	j, err := e.findJob(ctx, args.ID)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	rel, _ := filepath.Rel(j.workDir, j.logPath)
+	status := fmt.Sprintf("job %s %s\ncommand: %s\nlog: %s", j.id, j.summary(), j.command, rel)
+	tail, err := tailLines(j.logPath, jobStatusLines)
+	if err != nil {
+		return agent.ErrorResult("%s\ncouldn't read output: %v", status, err)
+	}
+	if tail != "" {
+		status += "\nlast output:\n" + tail
+	}
+	return agent.TextResult(status)
+}
+
+// listJobs lists the jobs started in the workspace of the run, oldest first.
+func (e *ToolEnv) listJobs(ctx context.Context) agent.ToolResult {
+	workDir, err := e.workDir(ctx)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	e.jobsMu.Lock()
+	var jobs []*job
+	for _, j := range e.jobs {
+		if j.workDir == workDir {
+			jobs = append(jobs, j)
+		}
+	}
+	e.jobsMu.Unlock()
+
+	if len(jobs) == 0 {
+		return agent.TextResult("no jobs since Vayuu started; logs of earlier jobs are in " + jobLogDir + "/")
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].started.Before(jobs[b].started) })
+
+	var sb strings.Builder
+	for _, j := range jobs {
+		fmt.Fprintf(&sb, "%s  %s  %s\n", j.id, j.summary(), j.command)
+	}
+	return agent.TextResult(strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// jobOutput is a tool function that returns the last lines of a background job's output, whether it is still running or not.
+func (e *ToolEnv) jobOutput(ctx context.Context, args jobOutputArgs) agent.ToolResult {
+	j, err := e.findJob(ctx, args.ID)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	lines := args.Lines
+	switch {
+	case lines <= 0:
+		lines = defaultOutputLines
+	case lines > maxOutputLines:
+		lines = maxOutputLines
+	}
+
+	tail, err := tailLines(j.logPath, lines)
+	if err != nil {
+		return agent.ErrorResult("read job output: %v", err)
+	}
+	if tail == "" {
+		tail = "(no output yet)"
+	}
+	return agent.ToolResult{
+		Content:  tail,
+		Metadata: map[string]any{"job_id": j.id, "state": string(j.currentState())},
+	}
+}
+
+// killJob is a tool function that stops a running background job and everything it started.
+func (e *ToolEnv) killJob(ctx context.Context, args killJobArgs) agent.ToolResult {
+	j, err := e.findJob(ctx, args.ID)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	j.mu.Lock()
+	if j.state != jobRunning {
+		j.mu.Unlock()
+		return agent.ErrorResult("job %s already %s", j.id, j.summary())
+	}
+	j.killed = true
+	j.mu.Unlock()
+
+	j.cancel()
+	select {
+	case <-j.done:
+		return agent.TextResult(fmt.Sprintf("job %s killed", j.id))
+	case <-time.After(commandWaitDelay + time.Second):
+		return agent.ErrorResult("job %s was told to stop but hasn't ended yet", j.id)
+	}
+}
+
+// killAllJobs kills the process groups of every running job and waits for them to end, so none outlive Vayuu.
+// The chats that started them aren't told.
+func (e *ToolEnv) killAllJobs() {
+	e.jobsMu.Lock()
+	var running []*job
+	for _, j := range e.jobs {
+		j.mu.Lock()
+		if j.state == jobRunning {
+			j.killed = true
+			running = append(running, j)
+		}
+		j.mu.Unlock()
+	}
+	e.jobsMu.Unlock()
+
+	for _, j := range running {
+		j.cancel()
+	}
+	deadline := time.After(commandWaitDelay + time.Second)
+	for _, j := range running {
+		select {
+		case <-j.done:
+		case <-deadline:
+			slog.Warn("job was killed but hasn't ended yet", "id", j.id)
+		}
+	}
+	if len(running) > 0 {
+		slog.Info("background jobs killed", "count", len(running))
+	}
+}
+
+// findJob returns a job started in the workspace of the run.
+func (e *ToolEnv) findJob(ctx context.Context, id string) (*job, error) {
+	workDir, err := e.workDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.jobsMu.Lock()
+	j, ok := e.jobs[strings.TrimSpace(id)]
+	e.jobsMu.Unlock()
+	if !ok || j.workDir != workDir {
+		return nil, fmt.Errorf("no job %q since Vayuu started; logs of earlier jobs are in %s/", id, jobLogDir)
+	}
+	return j, nil
+}
+
+// runningJobs counts the jobs that haven't ended yet, across all workspaces.
+func (e *ToolEnv) runningJobs() int {
+	e.jobsMu.Lock()
+	defer e.jobsMu.Unlock()
+
+	running := 0
+	for _, j := range e.jobs {
+		if j.currentState() == jobRunning {
+			running++
+		}
+	}
+	return running
+}
+
+// currentState returns the state of a job.
+func (j *job) currentState() jobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+// summary describes how far a job got, e.g. "running for 2m10s" or "exited with status 1 after 5s".
+func (j *job) summary() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.state == jobRunning {
+		return fmt.Sprintf("running for %v", time.Since(j.started).Round(time.Second))
+	}
+	took := j.ended.Sub(j.started).Round(time.Second)
+	if j.state == jobExited {
+		return fmt.Sprintf("exited with status %d after %v", j.exitCode, took)
+	}
+	return fmt.Sprintf("%s after %v", j.state, took)
+}
+
+// jobIcon marks the outcome of a job in notifications.
+func jobIcon(j *job) string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.state == jobExited && j.exitCode == 0 {
+		return "✅"
+	}
+	return "❌"
+}
+
+// tailLines returns the last lines of a file, reading at most maxOutputTailBytes from its end.
+func tailLines(path string, lines int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := max(info.Size()-maxOutputTailBytes, 0)
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
+	if err != nil {
+		return "", err
+	}
+	if offset > 0 {
+		// Drop the partial line the window starts in.
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	all := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n"), nil
+}
+
+// newJobID returns a random ID for a job, which also names its log.
+func newJobID() (string, error) {
+	b := make([]byte, jobIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
+// listDir is a tool function that lists a directory with the size and modification time of each entry, descending into subdirectories up to the given depth.
+// Entries ignored by .gitignore are left out unless include_ignored is set, and results come a page at a time.
+func (e *ToolEnv) listDir(ctx context.Context, args listDirArgs) agent.ToolResult {
+	dir, workDir, err := e.resolveDir(ctx, args.Path)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	if !isDirectory(dir) {
+		return agent.ErrorResult("path is not a directory; use read_file to read a file")
+	}
+
+	depth := args.Depth
+	switch {
+	case depth <= 0:
+		depth = defaultListDepth
+	case depth > maxListDepth:
+		depth = maxListDepth
+	}
+
+	p := newPage(args.Offset, args.Limit, defaultPageSize, maxPageSize)
+	w := &treeWalker{all: args.IncludeIgnored, maxDepth: depth}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	err = w.walk(workDir, dir, func(full string, d fs.DirEntry, _ int) error {
+		if !p.next() {
+			if p.more {
+				return errWalkDone
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, full)
+		name, size := filepath.ToSlash(rel), "-"
+		info, err := d.Info()
+		switch {
+		case err != nil:
+			fmt.Fprintf(tw, "%s\t?\t?\n", name)
+			return nil
+		case d.IsDir():
+			name += "/"
+		case d.Type()&fs.ModeSymlink != 0:
+			name += " ->"
+		default:
+			size = formatBytes(info.Size())
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, size, info.ModTime().Format("2006-01-02 15:04"))
+		return nil
+	})
+	if err != nil {
+		return agent.ErrorResult("listing %s: %v", displayPath(workDir, dir), err)
+	}
+	tw.Flush()
+
+	if p.shown == 0 {
+		if p.offset > 0 && p.seen > 0 {
+			return agent.TextResult(fmt.Sprintf("no entries past offset %d (%d in total)", p.offset, p.seen))
+		}
+		return agent.TextResult("no entries" + p.footer("entries", w))
+	}
+	return agent.ToolResult{
+		Content:  strings.TrimSuffix(sb.String(), "\n") + p.footer("entries", w),
+		Metadata: map[string]any{"entries": p.shown, "more": p.more},
+	}
+}
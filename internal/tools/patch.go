@@ -0,0 +1,329 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches the header of a hunk. The line ranges are optional, since a bare @@ is common in hand-written patches.
+var hunkHeader = regexp.MustCompile(`^@@(?: -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@)?`)
+
+// filePatch is the part of a unified diff that changes one file.
+type filePatch struct {
+	oldPath string // Empty for /dev/null, when the patch creates the file
+	newPath string // Empty for /dev/null, when the patch deletes the file
+	hunks   []hunk
+}
+
+// hunk is one block of changes, with the unchanged lines around them that locate it.
+type hunk struct {
+	oldStart     int // Line the hunk says it starts at in the old file, counting from 1; zero when unknown
+	lines        []diffOp
+	noNewline    bool // The new file doesn't end in a newline
+	oldNoNewline bool // The old file doesn't end in a newline, so the hunk reaches its end
+}
+
+// hunkMatch is where a hunk was found in a file, and how loosely.
+type hunkMatch struct {
+	at       int // Index of the first old line matched
+	head     int // Context lines dropped from the start of the hunk
+	tail     int // Context lines dropped from the end of the hunk
+	loose    bool
+	distance int // Lines between where the hunk said it starts and where it was found
+}
+
+// parsePatch splits a unified diff into its files. Lines of git headers such as "diff --git" and "index" are ignored.
+func parsePatch(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var files []filePatch
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			files = append(files, filePatch{oldPath: headerPath(line[4:]), newPath: headerPath(lines[i+1][4:])})
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if len(files) == 0 {
+				return nil, fmt.Errorf("hunk before any --- / +++ file header")
+			}
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			f := &files[len(files)-1]
+			f.hunks = append(f.hunks, h)
+			i = next - 1
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no --- / +++ file headers found; is this a unified diff?")
+	}
+
+	for i := range files {
+		f := &files[i]
+		if f.oldPath == "" && f.newPath == "" {
+			return nil, fmt.Errorf("file %d has /dev/null on both sides", i+1)
+		}
+		// Strip the a/ and b/ prefixes of git diffs.
+		if (f.oldPath == "" || strings.HasPrefix(f.oldPath, "a/")) && (f.newPath == "" || strings.HasPrefix(f.newPath, "b/")) {
+			f.oldPath = strings.TrimPrefix(f.oldPath, "a/")
+			f.newPath = strings.TrimPrefix(f.newPath, "b/")
+		}
+		if len(f.hunks) == 0 && f.newPath != "" {
+			return nil, fmt.Errorf("no hunks for %s", f.newPath)
+		}
+	}
+	return files, nil
+}
+
+// parseHunk parses the hunk whose header is lines[start] and returns it with the index of the line after it.
+// With line counts in the header the hunk ends when they are used up; without, at the next hunk or file header.
+func parseHunk(lines []string, start int) (hunk, int, error) {
+	m := hunkHeader.FindStringSubmatch(lines[start])
+	var h hunk
+	oldLeft, newLeft, counted := 0, 0, m[1] != ""
+	if counted {
+		h.oldStart, _ = strconv.Atoi(m[1])
+		oldLeft, newLeft = 1, 1
+		if m[2] != "" {
+			oldLeft, _ = strconv.Atoi(m[2])
+		}
+		if m[4] != "" {
+			newLeft, _ = strconv.Atoi(m[4])
+		}
+	}
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if counted && oldLeft <= 0 && newLeft <= 0 {
+			if strings.HasPrefix(line, `\`) {
+				h.markNoNewline()
+				i++
+			}
+			break
+		}
+		if !counted && (strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")) {
+			break
+		}
+
+		kind, text := byte(' '), ""
+		switch {
+		case line == "":
+			// An empty context line that lost its leading space, unless it ends the patch.
+			if !counted && i == len(lines)-1 {
+				continue
+			}
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			kind, text = line[0], line[1:]
+		case line[0] == '\\':
+			h.markNoNewline()
+			continue
+		default:
+			if counted {
+				return h, 0, fmt.Errorf("unexpected line in hunk %q: %q", lines[start], line)
+			}
+			// Trailing text after the last hunk of a patch without counts.
+			return h.trimmed(), i, nil
+		}
+
+		h.lines = append(h.lines, diffOp{kind, text})
+		if kind != '+' {
+			oldLeft--
+		}
+		if kind != '-' {
+			newLeft--
+		}
+	}
+	if counted && (oldLeft > 0 || newLeft > 0) {
+		return h, 0, fmt.Errorf("hunk %q ends early: %d old and %d new lines missing", lines[start], max(oldLeft, 0), max(newLeft, 0))
+	}
+	if !counted {
+		h = h.trimmed()
+	}
+	return h, i, nil
+}
+
+// markNoNewline records a "\ No newline at end of file" marker, which applies to the side of the line before it:
+// the old file for a removed line, the new file for an added one, and both for context.
+func (h *hunk) markNoNewline() {
+	if len(h.lines) == 0 {
+		return
+	}
+	kind := h.lines[len(h.lines)-1].kind
+	h.oldNoNewline = h.oldNoNewline || kind != '+'
+	h.noNewline = h.noNewline || kind != '-'
+}
+
+// trimmed drops the empty context lines at the end of a hunk without line counts, which are usually blank lines between it and what follows.
+func (h hunk) trimmed() hunk {
+	for len(h.lines) > 0 && h.lines[len(h.lines)-1] == (diffOp{' ', ""}) {
+		h.lines = h.lines[:len(h.lines)-1]
+	}
+	return h
+}
+
+// headerPath returns the path of a --- or +++ line, without a trailing timestamp; /dev/null becomes empty.
+func headerPath(s string) string {
+	s, _, _ = strings.Cut(s, "\t")
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	return s
+}
+
+// applyHunks applies hunks in order to the lines of a file. Each hunk is looked for near the line it names, first exactly,
+// then ignoring trailing and then all surrounding whitespace, then with up to patchFuzz context lines dropped from each end.
+// Lines kept as context keep their text from the file. It returns the new lines and a note for every hunk that didn't apply exactly.
+func applyHunks(name string, lines []string, hunks []hunk) ([]string, []string, error) {
+	var out, notes []string
+	pos, shift := 0, 0
+	for n, h := range hunks {
+		match, ok := findHunk(lines, pos, h, shift)
+		if !ok {
+			return nil, nil, fmt.Errorf("hunk %d of %s doesn't match the file; these lines weren't found after line %d:\n%s",
+				n+1, name, pos, hunkOldText(h))
+		}
+		if match.loose || match.head > 0 || match.tail > 0 || match.distance != 0 {
+			notes = append(notes, fmt.Sprintf("hunk %d of %s applied at line %d%s", n+1, name, match.at+1, match.describe()))
+		}
+
+		out = append(out, lines[pos:match.at]...)
+		j := match.at
+		for _, op := range h.lines[match.head : len(h.lines)-match.tail] {
+			switch op.kind {
+			case ' ':
+				out = append(out, lines[j])
+				j++
+			case '-':
+				j++
+			case '+':
+				out = append(out, op.line)
+			}
+		}
+		if h.oldStart > 0 {
+			shift = match.at - match.head - (h.oldStart - 1)
+		}
+		pos = j
+	}
+	return append(out, lines[pos:]...), notes, nil
+}
+
+// findHunk finds where the old lines of a hunk are in lines, at or after pos, closest to where the hunk says it starts plus shift.
+func findHunk(lines []string, pos int, h hunk, shift int) (hunkMatch, bool) {
+	head, tail := 0, 0
+	for head < len(h.lines) && h.lines[head].kind == ' ' {
+		head++
+	}
+	for tail < len(h.lines)-head && h.lines[len(h.lines)-1-tail].kind == ' ' {
+		tail++
+	}
+
+	hint := pos
+	if h.oldStart > 0 {
+		hint = max(h.oldStart-1+shift, pos)
+	}
+	for fuzz := 0; fuzz <= patchFuzz; fuzz++ {
+		dropHead, dropTail := min(fuzz, head), min(fuzz, tail)
+		if fuzz > 0 && dropHead+dropTail == 0 {
+			break
+		}
+		ops := h.lines[dropHead : len(h.lines)-dropTail]
+		var old []string
+		for _, op := range ops {
+			if op.kind != '+' {
+				old = append(old, op.line)
+			}
+		}
+		if len(old) == 0 {
+			if fuzz > 0 {
+				// Without any context left, the hunk could go anywhere.
+				break
+			}
+			// Nothing to look for: an addition goes where the hunk says, or at the end when it doesn't say.
+			at := len(lines)
+			if h.oldStart > 0 {
+				at = min(max(h.oldStart+shift, pos), len(lines))
+			}
+			return hunkMatch{at: at}, true
+		}
+
+		for _, norm := range []func(string) string{nil, trimRight, strings.TrimSpace} {
+			if at, ok := closestMatch(lines, old, pos, hint+dropHead, norm); ok {
+				m := hunkMatch{at: at, head: dropHead, tail: dropTail, loose: norm != nil}
+				if h.oldStart > 0 {
+					m.distance = at - dropHead - (h.oldStart - 1)
+				}
+				return m, true
+			}
+		}
+	}
+	return hunkMatch{}, false
+}
+
+// closestMatch returns the index at or after pos where block appears in lines, the one closest to hint when there are several.
+// Lines are compared after norm, when it isn't nil.
+func closestMatch(lines, block []string, pos, hint int, norm func(string) string) (int, bool) {
+	matchesAt := func(at int) bool {
+		for i, want := range block {
+			got := lines[at+i]
+			if norm != nil {
+				got, want = norm(got), norm(want)
+			}
+			if got != want {
+				return false
+			}
+		}
+		return true
+	}
+
+	last := len(lines) - len(block)
+	hint = min(max(hint, pos), max(last, pos))
+	for d := 0; hint-d >= pos || hint+d <= last; d++ {
+		if at := hint - d; at >= pos && at <= last && matchesAt(at) {
+			return at, true
+		}
+		if at := hint + d; d > 0 && at >= pos && at <= last && matchesAt(at) {
+			return at, true
+		}
+	}
+	return 0, false
+}
+
+// describe explains how a hunk was matched, for the notes of apply_patch.
+func (m hunkMatch) describe() string {
+	var how []string
+	if m.distance != 0 {
+		how = append(how, fmt.Sprintf("%+d lines from where it said", m.distance))
+	}
+	if m.loose {
+		how = append(how, "ignoring whitespace")
+	}
+	if m.head > 0 || m.tail > 0 {
+		how = append(how, fmt.Sprintf("without %d context line(s)", m.head+m.tail))
+	}
+	if len(how) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(how, ", ") + ")"
+}
+
+// hunkOldText returns the lines a hunk expects to find, for error messages.
+func hunkOldText(h hunk) string {
+	var sb strings.Builder
+	for _, op := range h.lines {
+		if op.kind != '+' {
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// trimRight drops trailing whitespace.
+func trimRight(s string) string {
+	return strings.TrimRight(s, " \t")
+}
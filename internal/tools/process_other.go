@@ -0,0 +1,10 @@
+//go:build !unix
+
+package tools
+
+import "os/exec"
+
+// killProcessGroup only bounds the wait for output on platforms without process groups.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = commandWaitDelay
+}
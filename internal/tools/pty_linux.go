@@ -0,0 +1,63 @@
+//go:build linux
+
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPTY opens a new pseudo-terminal and returns its master and slave ends. The master is non-blocking, so reads
+// from it can be interrupted by closing it. Echo is off: the model already knows what it typed.
+func openPTY() (*os.File, *os.File, error) {
+	master, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open pty: %w", err)
+	}
+	if err := unix.IoctlSetPointerInt(master, unix.TIOCSPTLCK, 0); err != nil {
+		unix.Close(master)
+		return nil, nil, fmt.Errorf("unlock pty: %w", err)
+	}
+	n, err := unix.IoctlGetUint32(master, unix.TIOCGPTN)
+	if err != nil {
+		unix.Close(master)
+		return nil, nil, fmt.Errorf("get pty number: %w", err)
+	}
+
+	slavePath := fmt.Sprintf("/dev/pts/%d", n)
+	slave, err := unix.Open(slavePath, unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		unix.Close(master)
+		return nil, nil, fmt.Errorf("open %s: %w", slavePath, err)
+	}
+
+	if termios, err := unix.IoctlGetTermios(slave, unix.TCGETS); err == nil {
+		termios.Lflag &^= unix.ECHO
+		_ = unix.IoctlSetTermios(slave, unix.TCSETS, termios)
+	}
+	_ = unix.IoctlSetWinsize(slave, unix.TIOCSWINSZ, &unix.Winsize{Row: sessionRows, Col: sessionCols})
+
+	return os.NewFile(uintptr(master), "/dev/ptmx"), os.NewFile(uintptr(slave), slavePath), nil
+}
+
+// attachPTY runs the command as the leader of a new session whose controlling terminal is the slave end of a pty,
+// so the shell gets job control and control keys reach its foreground process. Closing the session kills it.
+func attachPTY(cmd *exec.Cmd, slave *os.File) {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+}
+
+// killSession kills the process group of a session's shell.
+func killSession(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// openPTY is only implemented on Linux.
+func openPTY() (*os.File, *os.File, error) {
+	return nil, nil, fmt.Errorf("shell sessions are only supported on Linux")
+}
+
+// attachPTY is never reached, since openPTY fails.
+func attachPTY(cmd *exec.Cmd, slave *os.File) {}
+
+// killSession kills the shell of a session.
+func killSession(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
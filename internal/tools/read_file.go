@@ -1,54 +1,142 @@
 package tools
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
 )
 
-// readFile is a tool function that reads the content of a file or multiple files specified by their paths. It validates the file paths, checks for file size limits, and returns the content of the file(s) or any errors encountered during the process. The function supports both single string paths and arrays of string paths, providing formatted output for multiple files.
-func (e *ToolEnv) readFile(args map[string]any) string {
-	if pathStr, ok := args["path"].(string); ok {
-		return e.readSingleFile(pathStr)
+// readFile is a tool function that reads the content of a file or multiple files specified by their paths. It validates the file paths, checks for file size limits, and returns the content of the file(s) or any errors encountered during the process. Multiple files are returned one after another with a header per file; the call only fails when none of them could be read.
+// With an offset or limit, only that range of lines is read from each file, numbered, which also works for files too large to read whole.
+func (e *ToolEnv) readFile(ctx context.Context, args readFileArgs) agent.ToolResult {
+	if len(args.Path) == 0 {
+		return agent.ErrorResult("path must not be empty")
 	}
 
-	if pathSlice, ok := args["path"].([]any); ok {
-		var results []string
-		for i, p := range pathSlice {
-			pathStr, ok := p.(string)
-			if !ok {
-				return fmt.Sprintf("error: path[%d] must be a string", i)
-			}
-			results = append(results, fmt.Sprintf("=== %s ===\n%s", pathStr, e.readSingleFile(pathStr)))
+	if len(args.Path) == 1 {
+		content, err := e.readSingleFile(ctx, args.Path[0], args.Offset, args.Limit)
+		if err != nil {
+			return agent.ErrorResult("%v", err)
 		}
-		return strings.Join(results, "\n\n")
+		return agent.TextResult(content)
 	}
 
-	return "error: path must be a string or array of strings"
+	var results []string
+	failed := 0
+	for _, path := range args.Path {
+		content, err := e.readSingleFile(ctx, path, args.Offset, args.Limit)
+		if err != nil {
+			failed++
+			content = fmt.Sprintf("error: %v", err)
+		}
+		results = append(results, fmt.Sprintf("=== %s ===\n%s", path, content))
+	}
+
+	return agent.ToolResult{
+		Content:  strings.Join(results, "\n\n"),
+		IsError:  failed == len(args.Path),
+		Metadata: map[string]any{"files": len(args.Path), "failed": failed},
+	}
 }
 
 // readSingleFile is a helper function that reads the content of a single file specified by its relative path. It validates the file path, checks if it's a directory, verifies the file size against the defined limit, and returns the file content or any errors encountered during the process.
-func (e *ToolEnv) readSingleFile(relativePath string) string {
-	fullPath, err := e.validatePath(relativePath)
+// Binary files are refused rather than returned as garbled text. A nonzero offset or limit reads a range of lines instead, see readLines.
+func (e *ToolEnv) readSingleFile(ctx context.Context, relativePath string, offset, limit int) (string, error) {
+	fullPath, err := e.validatePath(ctx, relativePath)
 	if err != nil {
-		return fmt.Sprintf("error: %v", err)
+		return "", err
 	}
 
 	if isDirectory(fullPath) {
-		return "error: path is a directory, not a file"
+		return "", fmt.Errorf("path is a directory, not a file")
 	}
 
 	size, err := fileSize(fullPath)
 	if err != nil {
-		return fmt.Sprintf("error accessing file: %v", err)
+		return "", fmt.Errorf("accessing file: %w", err)
+	}
+	if offset > 0 || limit > 0 {
+		return readLines(fullPath, size, offset, limit)
 	}
 	if size > maxReadFileSize {
-		return fmt.Sprintf("error: file too large (%s, max %s)", formatBytes(size), formatBytes(maxReadFileSize))
+		return "", fmt.Errorf("file too large (%s, max %s); read it in parts with offset and limit", formatBytes(size), formatBytes(maxReadFileSize))
 	}
 
 	data, err := os.ReadFile(fullPath)
 	if err != nil {
-		return fmt.Sprintf("error reading file: %v", err)
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+	if isBinary(data) {
+		return "", binaryFileError(data, size)
+	}
+	return string(data), nil
+}
+
+// readLines reads limit lines of a file starting at line offset, counting from 1, and numbers them like cat -n.
+// The file is streamed, so its size doesn't matter, but the lines returned stop at maxReadFileSize. A note after them says where the next range starts.
+func readLines(path string, size int64, offset, limit int) (string, error) {
+	offset = max(offset, 1)
+	if limit <= 0 {
+		limit = defaultReadLines
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if head, _ := r.Peek(binarySniffBytes); isBinary(head) {
+		return "", binaryFileError(head, size)
 	}
-	return string(data)
+
+	var sb strings.Builder
+	last, total, cut := 0, 0, false
+	for {
+		line, err := r.ReadString('\n')
+		if line == "" && err != nil {
+			if err != io.EOF {
+				return "", fmt.Errorf("reading file: %w", err)
+			}
+			break
+		}
+		total++
+		if total < offset || total >= offset+limit || cut {
+			continue
+		}
+		numbered := fmt.Sprintf("%6d\t%s\n", total, strings.TrimRight(line, "\r\n"))
+		if sb.Len()+len(numbered) > maxReadFileSize {
+			cut = true
+			continue
+		}
+		sb.WriteString(numbered)
+		last = total
+	}
+
+	switch {
+	case total == 0:
+		return "(empty file)", nil
+	case offset > total:
+		return "", fmt.Errorf("offset %d is past the end of the file (%d lines)", offset, total)
+	case last == 0:
+		return "", fmt.Errorf("line %d alone is larger than %s", offset, formatBytes(maxReadFileSize))
+	case last < total:
+		fmt.Fprintf(&sb, "[lines %d-%d of %d; call again with offset=%d for more]", offset, last, total, last+1)
+	default:
+		fmt.Fprintf(&sb, "[lines %d-%d of %d]", offset, last, total)
+	}
+	return sb.String(), nil
+}
+
+// binaryFileError describes a binary file by its detected content type instead of returning its bytes.
+func binaryFileError(head []byte, size int64) error {
+	return fmt.Errorf("binary file (%s, %s), not shown; use view_image to look at images or send_file to share it",
+		http.DetectContentType(head), formatBytes(size))
 }
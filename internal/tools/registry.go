@@ -1,22 +1,80 @@
 package tools
 
+import (
+	"fmt"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
 // This file defines the registry of tools available to the agent, including their definitions and handlers. It provides a function to register all tools with the agent and builds the tool definitions based on the provided ToolEnv.
 func buildToolDefs(env *ToolEnv) []toolDef {
 	return []toolDef{
 		{
 			name:        "read_file",
-			description: "Read the contents of one or more files at the given path(s)",
+			description: "Read the contents of one or more files at the given path(s). With offset or limit, read only that range of lines, numbered; use this for large files.",
 			parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"path": map[string]any{
-						"type":  "array",
-						"items": map[string]any{"type": "string"},
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "List of file paths to read, relative to the workspace",
 					},
+					"offset": map[string]any{"type": "integer", "description": "First line to read, counting from 1"},
+					"limit":  map[string]any{"type": "integer", "description": "Number of lines to read (default 2000 when offset is set)"},
 				},
 				"required": []string{"path"},
 			},
-			handler: env.readFile,
+			handler: agent.TypedHandler(env.readFile),
+			access:  env.readsPaths("path"),
+		},
+		{
+			name:        "list_dir",
+			description: "List a directory with the size and modification time of each entry, optionally descending into subdirectories. Skips .git and files ignored by .gitignore.",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": pageParameters(map[string]any{
+					"path":            map[string]any{"type": "string", "description": "Directory to list, relative to the workspace (default: the workspace)"},
+					"depth":           map[string]any{"type": "integer", "description": "Levels to descend, 1 for just the directory's entries (default 1, max 10)"},
+					"include_ignored": map[string]any{"type": "boolean", "description": "Also list entries ignored by .gitignore"},
+				}, defaultPageSize, maxPageSize),
+			},
+			handler: agent.TypedHandler(env.listDir),
+			access:  env.readsTree("path"),
+		},
+		{
+			name:        "glob",
+			description: "Find files and directories by a pattern relative to path: * and ? match within a name, ** matches any number of directories and {a,b} either alternative, e.g. **/*.go or src/**/*.{ts,tsx}. Skips .git and files ignored by .gitignore.",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": pageParameters(map[string]any{
+					"pattern":         map[string]any{"type": "string", "description": "Glob pattern"},
+					"path":            map[string]any{"type": "string", "description": "Directory to search, relative to the workspace (default: the workspace)"},
+					"include_ignored": map[string]any{"type": "boolean", "description": "Also match entries ignored by .gitignore"},
+				}, defaultPageSize, maxPageSize),
+				"required": []string{"pattern"},
+			},
+			handler: agent.TypedHandler(env.glob),
+			access:  env.readsTree("path"),
+		},
+		{
+			name:        "grep",
+			description: "Search file contents for lines matching a regular expression (RE2 syntax). Returns path:line:text, with context lines as path-line-text. Skips binary files, .git and files ignored by .gitignore.",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": pageParameters(map[string]any{
+					"pattern":         map[string]any{"type": "string", "description": "Regular expression to search for"},
+					"path":            map[string]any{"type": "string", "description": "File or directory to search, relative to the workspace (default: the workspace)"},
+					"glob":            map[string]any{"type": "string", "description": "Only search files matching this glob, e.g. *.go or src/**/*.ts"},
+					"type":            map[string]any{"type": "string", "description": "Only search files of this type, e.g. go, py, js, ts, md, json, yaml"},
+					"ignore_case":     map[string]any{"type": "boolean", "description": "Match regardless of case"},
+					"context":         map[string]any{"type": "integer", "description": "Lines of context before and after each match (max 10)"},
+					"include_ignored": map[string]any{"type": "boolean", "description": "Also search files ignored by .gitignore"},
+				}, defaultGrepMatches, maxGrepMatches),
+				"required": []string{"pattern"},
+			},
+			handler: agent.TypedHandler(env.grep),
+			access:  env.readsTree("path"),
 		},
 		{
 			name:        "write_file",
@@ -29,7 +87,8 @@ func buildToolDefs(env *ToolEnv) []toolDef {
 				},
 				"required": []string{"path", "content"},
 			},
-			handler: env.writeFile,
+			handler: agent.TypedHandler(env.writeFile),
+			access:  env.writesPaths("path"),
 		},
 		{
 			name:        "execute_command",
@@ -38,17 +97,135 @@ func buildToolDefs(env *ToolEnv) []toolDef {
 				"type": "object",
 				"properties": map[string]any{
 					"command": map[string]any{
-						"type":  "array",
-						"items": map[string]any{"type": "string"},
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "List of bash commands, run one after another in the workspace",
 					},
 				},
 				"required": []string{"command"},
 			},
-			handler: env.executeCommand,
+			handler: agent.TypedHandler(env.executeCommand),
+			access:  runsCommands("command"),
+		},
+		{
+			name:        "start_job",
+			description: "Start a bash command in the background, for work that takes longer than execute_command allows, such as builds, downloads or data processing. Returns a job ID right away; the output is logged under jobs/ and the chat is told when the job ends.",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"command": map[string]any{"type": "string", "description": "Bash command, run in the workspace"},
+					"wake_agent": map[string]any{
+						"type":        "boolean",
+						"description": "When the job ends, wake you to summarize the result for the user",
+					},
+				},
+				"required": []string{"command"},
+			},
+			handler: agent.TypedHandler(env.startJob),
+			access:  runsCommands("command"),
+		},
+		{
+			name:        "job_status",
+			description: "Show whether a background job is running or how it ended, with its last output lines. Without an ID, list the jobs.",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]any{"type": "string", "description": "Job ID returned by start_job"},
+				},
+			},
+			handler: agent.TypedHandler(env.jobStatus),
+			access:  touchesNothing,
+		},
+		{
+			name:        "job_output",
+			description: "Read the last lines of a background job's output",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id":    map[string]any{"type": "string", "description": "Job ID returned by start_job"},
+					"lines": map[string]any{"type": "integer", "description": "Number of lines from the end (default 50, max 500)"},
+				},
+				"required": []string{"id"},
+			},
+			handler: agent.TypedHandler(env.jobOutput),
+			access:  touchesNothing,
+		},
+		{
+			name:        "kill_job",
+			description: "Stop a running background job and everything it started",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]any{"type": "string", "description": "Job ID returned by start_job"},
+				},
+				"required": []string{"id"},
+			},
+			handler: agent.TypedHandler(env.killJob),
+		},
+		{
+			name:        "shell_open",
+			description: "Open a persistent interactive bash session on a terminal in the workspace. Unlike execute_command, the current directory, variables and running programs carry over between calls, so it suits REPLs, interactive prompts and long-running programs. One session per chat; it closes after 30 minutes unused.",
+			parameters:  map[string]any{"type": "object", "properties": map[string]any{}},
+			handler:     agent.TypedHandler(env.shellOpen),
+			access:      touchesNothing,
+		},
+		{
+			name:        "shell_send",
+			description: "Type input into the shell session, followed by Enter, and return the output. Waits until the output is quiet for a second, or until it matches `until`.",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": sessionWaitParameters(map[string]any{
+					"input":      map[string]any{"type": "string", "description": "Text to type, e.g. a command or an answer to a prompt"},
+					"no_newline": map[string]any{"type": "boolean", "description": "Don't press Enter after the input"},
+				}),
+				"required": []string{"input"},
+			},
+			handler: agent.TypedHandler(env.shellSend),
+			access: env.typesIntoSession(func(args map[string]any) (string, error) {
+				input, _ := args["input"].(string)
+				noNewline, _ := args["no_newline"].(bool)
+				return sendInput(input, noNewline), nil
+			}),
+		},
+		{
+			name:        "shell_read",
+			description: "Return the shell session's output since the last call, waiting for more if there is none yet",
+			parameters: map[string]any{
+				"type":       "object",
+				"properties": sessionWaitParameters(map[string]any{}),
+			},
+			handler: agent.TypedHandler(env.shellRead),
+			access:  touchesNothing,
+		},
+		{
+			name:        "shell_keys",
+			description: "Send keys to the shell session, e.g. ctrl-c to interrupt the running program, ctrl-d to end input, or arrows and tab for interactive programs, and return the output that follows",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": sessionWaitParameters(map[string]any{
+					"keys": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "Keys in order: ctrl-a to ctrl-z, ctrl-\\, enter, tab, esc, backspace, space, up, down, left, right, home, end",
+					},
+				}),
+				"required": []string{"keys"},
+			},
+			handler: agent.TypedHandler(env.shellKeys),
+			access: env.typesIntoSession(func(args map[string]any) (string, error) {
+				return keysInput(argStrings(args["keys"]))
+			}),
+		},
+		{
+			name:        "shell_close",
+			description: "Close the shell session, ending everything running in it",
+			parameters:  map[string]any{"type": "object", "properties": map[string]any{}},
+			handler:     agent.TypedHandler(env.shellClose),
+			access:      touchesNothing,
 		},
 		{
 			name:        "send_file",
-			description: "Send a file (image, video, document) to the user via Telegram. Type is auto-detected from file extension.",
+			description: "Send a file (image, video, document) to the user via Telegram along with your reply. Type is auto-detected from file extension.",
 			parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
@@ -68,7 +245,21 @@ func buildToolDefs(env *ToolEnv) []toolDef {
 				},
 				"required": []string{"path"},
 			},
-			handler: env.sendFile,
+			handler: agent.TypedHandler(env.sendFile),
+			access:  env.readsPaths("path"),
+		},
+		{
+			name:        "view_image",
+			description: "Look at an image in the workspace (JPEG, PNG or GIF). Only works with vision-capable models.",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]any{"type": "string", "description": "Path to the image"},
+				},
+				"required": []string{"path"},
+			},
+			handler: agent.TypedHandler(env.viewImage),
+			access:  env.readsPaths("path"),
 		},
 		{
 			name:        "edit_file",
@@ -82,7 +273,58 @@ func buildToolDefs(env *ToolEnv) []toolDef {
 				},
 				"required": []string{"path", "old_string", "new_string"},
 			},
-			handler: env.editFile,
+			handler: agent.TypedHandler(env.editFile),
+			access:  env.writesPaths("path"),
+		},
+		{
+			name:        "apply_patch",
+			description: "Change one or more files in one step, all or nothing: either a unified diff (--- a/path, +++ b/path, @@ hunks; /dev/null creates or deletes a file) or a list of edits like edit_file. Context that moved or whose whitespace differs is still matched. Returns a diff of the changes.",
+			parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"patch": map[string]any{"type": "string", "description": "Unified diff with paths relative to the workspace"},
+					"edits": map[string]any{
+						"type":        "array",
+						"description": "Replacements applied in order, instead of a patch",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"path":        map[string]any{"type": "string", "description": "Path to the file to edit"},
+								"old_string":  map[string]any{"type": "string", "description": "Text to replace; must match once unless replace_all. Empty creates the file"},
+								"new_string":  map[string]any{"type": "string", "description": "Replacement text"},
+								"replace_all": map[string]any{"type": "boolean", "description": "Replace every occurrence"},
+							},
+							"required": []string{"path", "old_string", "new_string"},
+						},
+					},
+					"dry_run": map[string]any{"type": "boolean", "description": "Only show the diff, without writing anything"},
+				},
+			},
+			handler: agent.TypedHandler(env.applyPatch),
+			access:  env.writesPatchPaths,
 		},
 	}
 }
+
+// sessionWaitParameters adds the parameters saying how long a shell session call waits for output to properties.
+func sessionWaitParameters(properties map[string]any) map[string]any {
+	properties["until"] = map[string]any{
+		"type":        "string",
+		"description": "Regular expression; return as soon as the output matches it, e.g. a prompt, instead of waiting for a quiet second",
+	}
+	properties["timeout_seconds"] = map[string]any{
+		"type":        "integer",
+		"description": "Longest wait for output (default 15, max 120); the program keeps running after it",
+	}
+	return properties
+}
+
+// pageParameters adds the parameters selecting a page of results to properties.
+func pageParameters(properties map[string]any, defaultLimit, maxLimit int) map[string]any {
+	properties["offset"] = map[string]any{"type": "integer", "description": "Results to skip, to get the next page"}
+	properties["limit"] = map[string]any{
+		"type":        "integer",
+		"description": fmt.Sprintf("Results per page (default %d, max %d)", defaultLimit, maxLimit),
+	}
+	return properties
+}
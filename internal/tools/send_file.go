@@ -1,40 +1,36 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
-)
-
-// sendFile is a tool function that sends a file to a chat using the configured FileSender. It validates the file path, checks if the file exists and is not a directory, and then uses the FileSender to send the file with an optional caption. The function returns a success message or any errors encountered during the process.
-func (e *ToolEnv) sendFile(args map[string]any) string {
-	pathStr, ok := args["path"].(string)
-	if !ok {
-		return "error: path must be a string"
-	}
 
-	caption, _ := args["caption"].(string)
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
 
-	fullPath, err := e.validatePath(pathStr)
+// sendFile is a tool function that queues a file for delivery to the user. It validates the file path, checks if the file exists and is not a directory, and returns the file as an attachment that is sent along with the agent's reply, with an optional caption and type hint.
+func (e *ToolEnv) sendFile(ctx context.Context, args sendFileArgs) agent.ToolResult {
+	fullPath, err := e.validatePath(ctx, args.Path)
 	if err != nil {
-		return fmt.Sprintf("error: %v", err)
+		return agent.ErrorResult("%v", err)
 	}
 
 	if _, err := os.Stat(fullPath); err != nil {
-		return fmt.Sprintf("error: file not found: %v", err)
+		return agent.ErrorResult("file not found: %v", err)
 	}
 
 	if isDirectory(fullPath) {
-		return "error: path is a directory, not a file"
+		return agent.ErrorResult("path is a directory, not a file")
 	}
 
-	sender := e.getFileSender()
-	if sender == nil {
-		return "error: file sender not configured"
+	origin, _ := agent.OriginFrom(ctx)
+	return agent.ToolResult{
+		Content:  fmt.Sprintf("file will be sent with the reply: %s", args.Path),
+		Metadata: map[string]any{"channel": origin.Channel, "chat": origin.Chat},
+		Attachments: []agent.Attachment{{
+			Path:    fullPath,
+			Caption: args.Caption,
+			Type:    args.FileType,
+		}},
 	}
-
-	if err := sender(fullPath, caption); err != nil {
-		return fmt.Sprintf("error sending content: %v", err)
-	}
-
-	return fmt.Sprintf("content sent: %s", pathStr)
 }
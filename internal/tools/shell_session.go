@@ -0,0 +1,472 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
+// sessionKeys maps the key names shell_keys accepts to the bytes a terminal sends for them. ctrl-a to ctrl-z are handled apart.
+var sessionKeys = map[string]string{
+	"enter":     "\r",
+	"tab":       "\t",
+	"esc":       "\x1b",
+	"backspace": "\x7f",
+	"space":     " ",
+	"up":        "\x1b[A",
+	"down":      "\x1b[B",
+	"right":     "\x1b[C",
+	"left":      "\x1b[D",
+	"home":      "\x1b[H",
+	"end":       "\x1b[F",
+	"ctrl-\\":   "\x1c",
+}
+
+// ansiEscape matches terminal escape sequences: CSI sequences such as colors, OSC sequences such as titles, and two-byte escapes.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// shellOpen is a tool function that starts a persistent bash session on a pty for the chat, in the workspace of the run.
+// Unlike execute_command, state such as the current directory, variables and running programs carries over between calls.
+func (e *ToolEnv) shellOpen(ctx context.Context, _ shellOpenArgs) agent.ToolResult {
+	key, workDir, err := e.sessionKey(ctx)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	e.sessionsMu.Lock()
+	if s, ok := e.sessions[key]; ok && !s.hasExited() {
+		e.sessionsMu.Unlock()
+		return agent.ErrorResult("a shell session is already open here; use shell_send, or shell_close to start over")
+	}
+	open := 0
+	for _, s := range e.sessions {
+		if !s.hasExited() {
+			open++
+		}
+	}
+	e.sessionsMu.Unlock()
+	if open >= maxShellSessions {
+		return agent.ErrorResult("%d shell sessions are already open (max %d); close one first", open, maxShellSessions)
+	}
+
+	s, err := e.startSession(key, workDir)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	e.sessionsMu.Lock()
+	if old, ok := e.sessions[key]; ok {
+		go e.closeSession(old)
+	}
+	e.sessions[key] = s
+	e.sessionsMu.Unlock()
+
+	s.busy.Lock()
+	defer s.busy.Unlock()
+
+	// Wait for the first prompt, so a sandbox that fails to start is reported here.
+	out, err := s.read(ctx, regexp.MustCompile(regexp.QuoteMeta(strings.TrimSpace(shellPrompt))), sessionOpenWait)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	if s.hasExited() {
+		e.closeSession(s)
+		return agent.ErrorResult("shell exited right away: %s", strings.TrimSpace(out.text))
+	}
+	slog.Info("shell session opened", "dir", workDir, "pid", s.cmd.Process.Pid)
+	return agent.TextResult(fmt.Sprintf("opened a bash session in the workspace; the prompt is %q and it closes after %v unused.\n%s",
+		shellPrompt, sessionIdleTimeout, out.render()))
+}
+
+// shellSend is a tool function that types input into the session's shell, followed by Enter unless no_newline is set, and returns the output it produces.
+func (e *ToolEnv) shellSend(ctx context.Context, args shellSendArgs) agent.ToolResult {
+	input := sendInput(args.Input, args.NoNewline)
+	if input == "" {
+		return agent.ErrorResult("input is empty")
+	}
+	return e.sessionCall(ctx, input, args.sessionWaitArgs)
+}
+
+// shellRead is a tool function that returns the output the session's shell produced since the last call, waiting for more if there is none yet.
+func (e *ToolEnv) shellRead(ctx context.Context, args shellReadArgs) agent.ToolResult {
+	return e.sessionCall(ctx, "", args.sessionWaitArgs)
+}
+
+// shellKeys is a tool function that sends control keys to the session's shell, e.g. ctrl-c to interrupt the running program, and returns the output that follows.
+func (e *ToolEnv) shellKeys(ctx context.Context, args shellKeysArgs) agent.ToolResult {
+	if len(args.Keys) == 0 {
+		return agent.ErrorResult("no keys given")
+	}
+	input, err := keysInput(args.Keys)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	return e.sessionCall(ctx, input, args.sessionWaitArgs)
+}
+
+// shellClose is a tool function that ends the session's shell and everything running in it.
+func (e *ToolEnv) shellClose(ctx context.Context, _ shellCloseArgs) agent.ToolResult {
+	s, err := e.findSession(ctx)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	e.closeSession(s)
+	return agent.TextResult("shell session closed")
+}
+
+// sessionCall writes input, if any, to the session's shell and waits for its output as the wait arguments say.
+func (e *ToolEnv) sessionCall(ctx context.Context, input string, wait sessionWaitArgs) agent.ToolResult {
+	var until *regexp.Regexp
+	if wait.Until != "" {
+		re, err := regexp.Compile(wait.Until)
+		if err != nil {
+			return agent.ErrorResult("invalid until pattern: %v", err)
+		}
+		until = re
+	}
+	timeout := defaultSessionWait
+	if wait.TimeoutSeconds > 0 {
+		timeout = min(time.Duration(wait.TimeoutSeconds)*time.Second, maxSessionWait)
+	}
+
+	s, err := e.findSession(ctx)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	s.busy.Lock()
+	defer s.busy.Unlock()
+	s.idle.Reset(sessionIdleTimeout)
+
+	if input != "" {
+		if _, err := s.pty.Write([]byte(input)); err != nil {
+			e.closeSession(s)
+			return agent.ErrorResult("write to shell: %v; the session is closed", err)
+		}
+		s.mu.Lock()
+		_, s.line = typeLines(s.line, input)
+		s.mu.Unlock()
+	}
+
+	out, err := s.read(ctx, until, timeout)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+	text := out.render()
+	switch {
+	case s.hasExited() && out.pending == 0:
+		e.closeSession(s)
+		text += "\n[the shell exited; the session is closed]"
+	case until != nil && !out.matched && out.pending == 0:
+		text += fmt.Sprintf("\n[no output matched %q within %v; the program may still be running]", wait.Until, timeout)
+	}
+	return agent.TextResult(text)
+}
+
+// sessionKey returns the key of the run's session: one per chat and workspace.
+func (e *ToolEnv) sessionKey(ctx context.Context) (string, string, error) {
+	workDir, err := e.workDir(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	origin, _ := agent.OriginFrom(ctx)
+	return origin.Key() + " " + workDir, workDir, nil
+}
+
+// submittedLines returns the lines input would submit to the run's session with Enter, counting what earlier calls typed
+// without one, so a command typed in pieces is seen whole. Without an open session, the lines of input alone are returned.
+func (e *ToolEnv) submittedLines(ctx context.Context, input string) []string {
+	var line string
+	if s, err := e.findSession(ctx); err == nil {
+		s.mu.Lock()
+		line = s.line
+		s.mu.Unlock()
+	}
+	lines, _ := typeLines(line, input)
+	return lines
+}
+
+// typeLines applies input to the line being typed, as the terminal's line editing does, and returns the lines that
+// Enter submits, leaving out blank ones, and the line left typed. Ctrl-c, ctrl-\ and ctrl-u discard the line,
+// ctrl-w the last word and backspace the last character.
+func typeLines(line, input string) ([]string, string) {
+	var submitted []string
+	for _, r := range input {
+		switch r {
+		case '\r', '\n':
+			if strings.TrimSpace(line) != "" {
+				submitted = append(submitted, line)
+			}
+			line = ""
+		case '\x03', '\x1c', '\x15':
+			line = ""
+		case '\x17':
+			line = strings.TrimRight(line, " \t")
+			line = line[:strings.LastIndexAny(line, " \t")+1]
+		case '\x7f', '\b':
+			_, size := utf8.DecodeLastRuneInString(line)
+			line = line[:len(line)-size]
+		case '\x04':
+			// Ctrl-d hands the line over without ending it, or ends the input if it is empty.
+		default:
+			line += string(r)
+		}
+	}
+	return submitted, line
+}
+
+// sendInput returns what shell_send types: the input, followed by Enter unless noNewline is set.
+func sendInput(input string, noNewline bool) string {
+	if !noNewline {
+		input += "\n"
+	}
+	return input
+}
+
+// keysInput returns the bytes shell_keys sends for the named keys.
+func keysInput(keys []string) (string, error) {
+	var input strings.Builder
+	for _, name := range keys {
+		seq, err := keySequence(name)
+		if err != nil {
+			return "", err
+		}
+		input.WriteString(seq)
+	}
+	return input.String(), nil
+}
+
+// findSession returns the open session of the run's chat and workspace.
+func (e *ToolEnv) findSession(ctx context.Context) (*shellSession, error) {
+	key, _, err := e.sessionKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.sessionsMu.Lock()
+	s, ok := e.sessions[key]
+	e.sessionsMu.Unlock()
+	if !ok {
+		return nil, errors.New("no shell session is open here; start one with shell_open")
+	}
+	return s, nil
+}
+
+// startSession starts an interactive bash on a new pty in workDir, confined by the sandbox like other commands.
+func (e *ToolEnv) startSession(key, workDir string) (*shellSession, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	defer slave.Close()
+
+	// The session outlives the run that opened it; it ends when closed or idle.
+	// Bash ignores a prompt set in its environment, so it comes from an rcfile instead of the user's own.
+	rc := fmt.Sprintf("PS1='%s' PS2='> '", shellPrompt)
+	cmd := e.Sandbox.Command(context.Background(), workDir, fmt.Sprintf("exec bash --noprofile --rcfile <(echo %q) --noediting -i", rc))
+	cmd.Env = append(os.Environ(), "TERM=dumb", "PAGER=cat", "GIT_PAGER=cat", "HISTFILE=/dev/null")
+	attachPTY(cmd, slave)
+	if err := cmd.Start(); err != nil {
+		master.Close()
+		return nil, fmt.Errorf("start shell: %w", err)
+	}
+
+	s := &shellSession{
+		key:     key,
+		workDir: workDir,
+		cmd:     cmd,
+		pty:     master,
+		exited:  make(chan struct{}),
+		changed: make(chan struct{}, 1),
+	}
+	s.idle = time.AfterFunc(sessionIdleTimeout, func() {
+		slog.Info("closing idle shell session", "dir", workDir)
+		e.closeSession(s)
+	})
+	go s.pump()
+	go func() {
+		err := cmd.Wait()
+		s.mu.Lock()
+		s.exitErr = err
+		s.mu.Unlock()
+		close(s.exited)
+		s.signal()
+	}()
+	return s, nil
+}
+
+// closeSession forgets a session and kills its shell. Hanging up the pty also sends SIGHUP to the shell's jobs.
+func (e *ToolEnv) closeSession(s *shellSession) {
+	e.sessionsMu.Lock()
+	if e.sessions[s.key] == s {
+		delete(e.sessions, s.key)
+	}
+	e.sessionsMu.Unlock()
+
+	s.idle.Stop()
+	s.pty.Close()
+	if !s.hasExited() {
+		killSession(s.cmd)
+	}
+	select {
+	case <-s.exited:
+	case <-time.After(commandWaitDelay):
+		slog.Warn("shell session was killed but hasn't exited yet", "dir", s.workDir)
+	}
+}
+
+// pump copies the shell's output into the session until the pty is closed or hung up, dropping the oldest output beyond maxSessionBuffer.
+func (s *shellSession) pump() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.pty.Read(buf)
+		if n > 0 {
+			s.mu.Lock()
+			s.output = append(s.output, buf[:n]...)
+			if over := len(s.output) - maxSessionBuffer; over > 0 {
+				s.output = s.output[over:]
+				s.dropped += over
+			}
+			s.mu.Unlock()
+			s.signal()
+		}
+		if err != nil {
+			// EIO once nothing has the terminal open any more, or the pty was closed.
+			return
+		}
+	}
+}
+
+// signal wakes a read waiting for output without blocking.
+func (s *shellSession) signal() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// hasExited reports whether the session's shell has exited.
+func (s *shellSession) hasExited() bool {
+	select {
+	case <-s.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// sessionOutput is what a read took from a session.
+type sessionOutput struct {
+	text    string
+	dropped int  // Bytes lost before text because nobody read them in time
+	pending int  // Bytes left for the next read
+	matched bool // The until pattern matched
+}
+
+// read waits until the output matches until, or, without a pattern, until the shell has been quiet for sessionQuietPeriod, and takes up to maxSessionRead bytes of it.
+// It stops waiting after timeout, or once the shell has exited and gone quiet, returning what there is.
+func (s *shellSession) read(ctx context.Context, until *regexp.Regexp, timeout time.Duration) (sessionOutput, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	quiet := time.NewTimer(sessionQuietPeriod)
+	defer quiet.Stop()
+
+	var out sessionOutput
+wait:
+	for {
+		if until != nil {
+			s.mu.Lock()
+			out.matched = until.MatchString(cleanOutput(s.output))
+			s.mu.Unlock()
+			if out.matched {
+				break
+			}
+		}
+		select {
+		case <-s.changed:
+			quiet.Reset(sessionQuietPeriod)
+		case <-quiet.C:
+			if until == nil || s.hasExited() {
+				break wait
+			}
+		case <-deadline.C:
+			break wait
+		case <-ctx.Done():
+			return out, ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := cutPoint(s.output, maxSessionRead)
+	out.text = cleanOutput(s.output[:n])
+	out.dropped = s.dropped
+	out.pending = len(s.output) - n
+	s.output = append([]byte(nil), s.output[n:]...)
+	s.dropped = 0
+	return out, nil
+}
+
+// render formats output taken from a session for the model, noting output that was dropped or is still waiting.
+func (o sessionOutput) render() string {
+	text := o.text
+	if text == "" {
+		text = "(no output)"
+	}
+	if o.dropped > 0 {
+		text = fmt.Sprintf("[%d bytes of earlier output were dropped]\n", o.dropped) + text
+	}
+	if o.pending > 0 {
+		text += fmt.Sprintf("\n[%d more bytes of output; call shell_read for them]", o.pending)
+	}
+	return text
+}
+
+// cutPoint returns how much of output to take to stay within limit, ending at a line break when there is one and never inside a UTF-8 character.
+func cutPoint(output []byte, limit int) int {
+	if len(output) <= limit {
+		return len(output)
+	}
+	if i := bytes.LastIndexByte(output[:limit], '\n'); i >= 0 {
+		return i + 1
+	}
+	n := limit
+	for n > 0 && !utf8.RuneStart(output[n]) {
+		n--
+	}
+	return n
+}
+
+// cleanOutput turns raw terminal output into plain text: escape sequences are removed, and a line overwritten with carriage returns keeps only its last version.
+func cleanOutput(raw []byte) string {
+	text := ansiEscape.ReplaceAllString(string(raw), "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.Contains(text, "\r") {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		lines[i] = line[strings.LastIndexByte(line, '\r')+1:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// keySequence returns the bytes sent for a key name, e.g. "ctrl-c", "enter" or "up".
+func keySequence(name string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if seq, ok := sessionKeys[key]; ok {
+		return seq, nil
+	}
+	if letter, ok := strings.CutPrefix(key, "ctrl-"); ok && len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z' {
+		return string(rune(letter[0] - 'a' + 1)), nil
+	}
+	return "", fmt.Errorf("unknown key %q; use ctrl-a to ctrl-z, ctrl-\\, enter, tab, esc, backspace, space, up, down, left, right, home or end", name)
+}
@@ -1,21 +1,205 @@
 package tools
 
 import (
+	"context"
+	"os"
+	"os/exec"
 	"sync"
-)
+	"time"
 
-type FileSenderFunc func(content, caption string) error
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+	"github.com/Shreehari-Acharya/vayuu/internal/sandbox"
+)
 
 type ToolEnv struct {
-	WorkDir       string
-	FileSender    FileSenderFunc
-	CurrentChatID int64
-	mu            sync.RWMutex
+	WorkDir  string
+	Sandbox  *sandbox.Sandbox // Confines shell commands; nil runs them directly
+	Notifier Notifier         // Tells chats about background jobs that ended; nil leaves them to job_status
+
+	jobsMu sync.Mutex
+	jobs   map[string]*job // Background jobs started since Vayuu started, by ID
+
+	sessionsMu sync.Mutex
+	sessions   map[string]*shellSession // Open shell sessions, by chat and workspace
+}
+
+// Notifier reaches the chat a run came from after the run is over.
+type Notifier interface {
+	// Notify sends Markdown text to the chat.
+	Notify(ctx context.Context, origin agent.Origin, text string) error
+
+	// Wake runs the agent on text in the chat, on behalf of the user who started the original run.
+	Wake(ctx context.Context, origin agent.Origin, text string) error
+}
+
+// job is a shell command running in the background, started by start_job.
+type job struct {
+	id      string
+	command string
+	workDir string // Workspace of the run that started it; only runs in the same workspace see the job
+	logPath string
+	started time.Time
+
+	origin    agent.Origin // Chat told when the job ends
+	hasOrigin bool
+	wake      bool // Wake the agent to summarize the result when the job ends
+
+	cancel context.CancelFunc
+	done   chan struct{} // Closed once the job has ended and its state is final
+
+	mu       sync.Mutex
+	state    jobState
+	exitCode int
+	ended    time.Time
+	killed   bool // Stopped by kill_job
+}
+
+// jobState is where a background job is in its life.
+type jobState string
+
+// shellSession is an interactive bash on a pty, opened by shell_open and kept until it is closed, exits or sits idle.
+type shellSession struct {
+	key     string
+	workDir string
+	cmd     *exec.Cmd
+	pty     *os.File      // Master end; the shell's input and output
+	idle    *time.Timer   // Closes the session after sessionIdleTimeout without use
+	exited  chan struct{} // Closed once the shell has exited
+
+	// A call holds busy while it uses the session, so the output of one call isn't read by another.
+	busy sync.Mutex
+
+	mu      sync.Mutex
+	line    string        // Input typed since the last Enter; the policy sees it whole once it is submitted
+	output  []byte        // Output not returned yet, at most maxSessionBuffer bytes
+	dropped int           // Bytes dropped from the start of output since the last read
+	changed chan struct{} // Signalled when output arrives or the shell exits
+	exitErr error
 }
 
 type toolDef struct {
 	name        string
 	description string
 	parameters  map[string]any
-	handler     func(map[string]any) string
-}
\ No newline at end of file
+	handler     agent.ToolFunc
+	access      agent.AccessFunc // nil runs the tool exclusively
+}
+
+type readFileArgs struct {
+	Path   []string `json:"path"`
+	Offset int      `json:"offset"` // First line to read, counting from 1; with limit, switches to numbered lines
+	Limit  int      `json:"limit"`  // Lines to read
+}
+
+type writeFileArgs struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+type editFileArgs struct {
+	Path      string `json:"path"`
+	OldString string `json:"old_string"`
+	NewString string `json:"new_string"`
+}
+
+type executeCommandArgs struct {
+	Command []string `json:"command"`
+}
+
+type viewImageArgs struct {
+	Path string `json:"path"`
+}
+
+type sendFileArgs struct {
+	Path     string `json:"path"`
+	Caption  string `json:"caption"`
+	FileType string `json:"file_type"`
+}
+
+type startJobArgs struct {
+	Command   string `json:"command"`
+	WakeAgent bool   `json:"wake_agent"`
+}
+
+type jobStatusArgs struct {
+	ID string `json:"id"`
+}
+
+type jobOutputArgs struct {
+	ID    string `json:"id"`
+	Lines int    `json:"lines"`
+}
+
+type killJobArgs struct {
+	ID string `json:"id"`
+}
+
+// sessionWaitArgs say how long a shell session call waits for output.
+type sessionWaitArgs struct {
+	Until          string `json:"until"` // Regular expression; waits for matching output instead of a quiet period
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+type shellOpenArgs struct{}
+
+type shellSendArgs struct {
+	Input     string `json:"input"`
+	NoNewline bool   `json:"no_newline"`
+	sessionWaitArgs
+}
+
+type shellReadArgs struct {
+	sessionWaitArgs
+}
+
+type shellKeysArgs struct {
+	Keys []string `json:"keys"`
+	sessionWaitArgs
+}
+
+type shellCloseArgs struct{}
+
+// pageArgs select a page of the results of a search.
+type pageArgs struct {
+	Offset int `json:"offset"` // Results to skip
+	Limit  int `json:"limit"`
+}
+
+type listDirArgs struct {
+	Path           string `json:"path"`
+	Depth          int    `json:"depth"`
+	IncludeIgnored bool   `json:"include_ignored"`
+	pageArgs
+}
+
+type globArgs struct {
+	Pattern        string `json:"pattern"`
+	Path           string `json:"path"`
+	IncludeIgnored bool   `json:"include_ignored"`
+	pageArgs
+}
+
+type grepArgs struct {
+	Pattern        string `json:"pattern"`
+	Path           string `json:"path"`
+	Glob           string `json:"glob"`
+	Type           string `json:"type"`
+	IgnoreCase     bool   `json:"ignore_case"`
+	Context        int    `json:"context"`
+	IncludeIgnored bool   `json:"include_ignored"`
+	pageArgs
+}
+
+type applyPatchArgs struct {
+	Patch  string      `json:"patch"` // Unified diff
+	Edits  []patchEdit `json:"edits"`
+	DryRun bool        `json:"dry_run"`
+}
+
+// patchEdit is one replacement of an apply_patch edit list, like an edit_file call.
+type patchEdit struct {
+	Path       string `json:"path"`
+	OldString  string `json:"old_string"` // Empty creates the file
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all"`
+}
@@ -1,19 +1,26 @@
 package tools
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
 )
 
-// validatePath is a helper function that validates and resolves a relative file path against the ToolEnv's working directory. It checks for empty paths, handles paths starting with "~/", and ensures that the resolved path does not allow for path traversal outside of the working directory. The function returns the cleaned full path or an error if the validation fails.
-func (e *ToolEnv) validatePath(relativePath string) (string, error) {
+// validatePath is a helper function that validates and resolves a relative file path against the workspace of the current run. It checks for empty paths, handles paths starting with "~/", and ensures that the resolved path does not allow for path traversal outside of the working directory. The function returns the cleaned full path or an error if the validation fails.
+func (e *ToolEnv) validatePath(ctx context.Context, relativePath string) (string, error) {
 	if strings.TrimSpace(relativePath) == "" {
 		return "", fmt.Errorf("path must not be empty")
 	}
 
 	if strings.HasPrefix(relativePath, "~/") {
+		if agent.ScopeFrom(ctx).Confined {
+			return "", fmt.Errorf("~/ paths are outside your workspace and only available to admins: %s", relativePath)
+		}
 		home, err := os.UserHomeDir()
 		if err != nil {
 			return "", fmt.Errorf("resolve home directory: %w", err)
@@ -21,14 +28,43 @@ func (e *ToolEnv) validatePath(relativePath string) (string, error) {
 		return filepath.Clean(filepath.Join(home, relativePath[2:])), nil
 	}
 
-	fullPath := filepath.Clean(filepath.Join(e.WorkDir, relativePath))
-	cleanWorkDir := filepath.Clean(e.WorkDir)
-	if !strings.HasPrefix(fullPath, cleanWorkDir) {
+	workDir, err := e.workDir(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	fullPath := filepath.Clean(filepath.Join(workDir, relativePath))
+	cleanWorkDir := filepath.Clean(workDir)
+	if !isWithin(cleanWorkDir, fullPath) {
 		return "", fmt.Errorf("path traversal not allowed: %s", relativePath)
 	}
 	return fullPath, nil
 }
 
+// workDir returns the workspace of the current run: the user's workspace when the run is scoped to one,
+// otherwise the shared workspace. A user workspace is created on first use.
+func (e *ToolEnv) workDir(ctx context.Context) (string, error) {
+	dir := agent.ScopeFrom(ctx).WorkDir
+	if dir == "" {
+		return e.WorkDir, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("create workspace: %w", err)
+	}
+	return dir, nil
+}
+
+// isWithin reports whether path is dir or lies under it. A sibling that merely shares the prefix, like /work-other for /work, is not within it.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isBinary reports whether data looks like the start of a binary file rather than text: it contains a NUL byte within the first binarySniffBytes.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), binarySniffBytes)], 0) >= 0
+}
+
 // isDirectory checks if the given path is a directory. It returns true if the path exists and is a directory, and false otherwise.
 func isDirectory(path string) bool {
 	info, err := os.Stat(path)
@@ -63,3 +99,106 @@ func formatBytes(bytes int64) string {
 		return fmt.Sprintf("%d B", bytes)
 	}
 }
+
+// readsPaths returns an access function for tools that only read the path(s) in the given argument.
+func (e *ToolEnv) readsPaths(arg string) agent.AccessFunc {
+	return func(ctx context.Context, args map[string]any) agent.ToolAccess {
+		return agent.ToolAccess{Reads: e.argPaths(ctx, args[arg])}
+	}
+}
+
+// writesPaths returns an access function for tools that modify the path(s) in the given argument.
+func (e *ToolEnv) writesPaths(arg string) agent.AccessFunc {
+	return func(ctx context.Context, args map[string]any) agent.ToolAccess {
+		return agent.ToolAccess{Writes: e.argPaths(ctx, args[arg])}
+	}
+}
+
+// readsTree returns an access function for tools that read the directory tree at the path in the given argument, or the whole workspace when it is unset.
+func (e *ToolEnv) readsTree(arg string) agent.AccessFunc {
+	return func(ctx context.Context, args map[string]any) agent.ToolAccess {
+		value := args[arg]
+		if s, _ := value.(string); strings.TrimSpace(s) == "" {
+			value = "."
+		}
+		return agent.ToolAccess{Reads: e.argPaths(ctx, value)}
+	}
+}
+
+// writesPatchPaths is the access of apply_patch: it writes the files named in the diff or the edit list.
+func (e *ToolEnv) writesPatchPaths(ctx context.Context, args map[string]any) agent.ToolAccess {
+	var paths []any
+	if patch, ok := args["patch"].(string); ok {
+		if files, err := parsePatch(patch); err == nil {
+			for _, f := range files {
+				for _, p := range []string{f.oldPath, f.newPath} {
+					if p != "" {
+						paths = append(paths, p)
+					}
+				}
+			}
+		}
+	}
+	if edits, ok := args["edits"].([]any); ok {
+		for _, edit := range edits {
+			if m, ok := edit.(map[string]any); ok {
+				paths = append(paths, m["path"])
+			}
+		}
+	}
+	return agent.ToolAccess{Writes: e.argPaths(ctx, paths)}
+}
+
+// touchesNothing is the access of tools that only look at Vayuu's own state, which can run alongside any other call.
+func touchesNothing(ctx context.Context, args map[string]any) agent.ToolAccess {
+	return agent.ToolAccess{}
+}
+
+// runsCommands returns an access function for tools that run the shell command(s) in the given argument.
+// A command can touch anything, so the call runs on its own; the commands are reported for the policy.
+func runsCommands(arg string) agent.AccessFunc {
+	return func(ctx context.Context, args map[string]any) agent.ToolAccess {
+		return agent.ToolAccess{Exclusive: true, Commands: argStrings(args[arg])}
+	}
+}
+
+// typesIntoSession returns an access function for tools that type into the run's shell session. Their commands are the
+// lines they submit with Enter, including what earlier calls typed into the line, so the policy sees each line whole.
+// input returns what a call types, from its arguments.
+func (e *ToolEnv) typesIntoSession(input func(args map[string]any) (string, error)) agent.AccessFunc {
+	return func(ctx context.Context, args map[string]any) agent.ToolAccess {
+		access := agent.ToolAccess{Exclusive: true}
+		if text, err := input(args); err == nil {
+			access.Commands = e.submittedLines(ctx, text)
+		}
+		return access
+	}
+}
+
+// argPaths resolves a string or array-of-strings path argument. Invalid paths are skipped since the tool rejects them anyway.
+func (e *ToolEnv) argPaths(ctx context.Context, value any) []string {
+	raw := argStrings(value)
+	paths := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if full, err := e.validatePath(ctx, p); err == nil {
+			paths = append(paths, full)
+		}
+	}
+	return paths
+}
+
+// argStrings returns the values of a string or array-of-strings argument.
+func argStrings(value any) []string {
+	var values []string
+	switch v := value.(type) {
+	case string:
+		values = append(values, v)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
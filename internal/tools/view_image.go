@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register the GIF decoder
+	_ "image/jpeg" // Register the JPEG decoder
+	_ "image/png"  // Register the PNG decoder
+	"os"
+
+	"github.com/Shreehari-Acharya/vayuu/internal/agent"
+)
+
+// viewImage is a tool function that shows an image from the workspace to the model. It validates the path and checks that the file is a readable JPEG, PNG or GIF image; the agent then attaches the image, downscaled, to the conversation.
+func (e *ToolEnv) viewImage(ctx context.Context, args viewImageArgs) agent.ToolResult {
+	fullPath, err := e.validatePath(ctx, args.Path)
+	if err != nil {
+		return agent.ErrorResult("%v", err)
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return agent.ErrorResult("file not found: %v", err)
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return agent.ErrorResult("not a supported image (JPEG, PNG or GIF): %v", err)
+	}
+
+	return agent.ToolResult{
+		Content:  fmt.Sprintf("%s is a %dx%d %s image; it is attached below", args.Path, cfg.Width, cfg.Height, format),
+		Images:   []string{fullPath},
+		Metadata: map[string]any{"format": format, "width": cfg.Width, "height": cfg.Height},
+	}
+}
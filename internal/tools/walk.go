@@ -0,0 +1,246 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// errWalkDone ends a walk early, once a tool has all the results it can return.
+var errWalkDone = errors.New("walk done")
+
+// treeWalker walks a directory tree in lexical order, skipping .git and, unless all is set, whatever .gitignore files exclude.
+// Symbolic links are listed but not followed.
+type treeWalker struct {
+	all      bool
+	maxDepth int // Deepest level visited below the root, where 1 is its entries; zero means no limit
+
+	rules   []ignoreRule // Rules of the .gitignore files on the current path, outermost first
+	visited int
+	stopped bool // Gave up after maxWalkEntries
+}
+
+// ignoreRule is one pattern line of a .gitignore file.
+type ignoreRule struct {
+	dir      string // Directory of the .gitignore file
+	pattern  string
+	negate   bool // A "!" line, re-including what earlier rules excluded
+	dirOnly  bool // Ends in "/", so only matches directories
+	anchored bool // Contains a "/", so it matches the path from dir rather than any name
+}
+
+// walk calls fn for every entry under root, which is a directory within top. depth is 1 for the entries of root.
+// The .gitignore files from top down to root apply, as well as those found on the way. fn may return fs.SkipDir to not descend into a directory, or errWalkDone to end the walk.
+func (w *treeWalker) walk(top, root string, fn func(path string, d fs.DirEntry, depth int) error) error {
+	if !w.all && root != top && isWithin(top, root) {
+		var dirs []string
+		for dir := filepath.Dir(root); isWithin(top, dir); dir = filepath.Dir(dir) {
+			dirs = append(dirs, dir)
+			if dir == top {
+				break
+			}
+		}
+		for i := len(dirs) - 1; i >= 0; i-- {
+			w.rules = append(w.rules, readIgnoreFile(dirs[i])...)
+		}
+	}
+	err := w.walkDir(root, 1, fn)
+	if errors.Is(err, errWalkDone) {
+		return nil
+	}
+	return err
+}
+
+// walkDir visits the entries of dir, applying its .gitignore for the entries below it.
+func (w *treeWalker) walkDir(dir string, depth int, fn func(path string, d fs.DirEntry, depth int) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if depth == 1 {
+			return err
+		}
+		// Unreadable subdirectories are skipped rather than failing the whole walk.
+		return nil
+	}
+
+	if !w.all {
+		n := len(w.rules)
+		w.rules = append(w.rules, readIgnoreFile(dir)...)
+		defer func() { w.rules = w.rules[:n] }()
+	}
+
+	for _, entry := range entries {
+		full := filepath.Join(dir, entry.Name())
+		if entry.Name() == ".git" && entry.IsDir() || !w.all && w.ignored(full, entry.IsDir()) {
+			continue
+		}
+		if w.visited++; w.visited > maxWalkEntries {
+			w.stopped = true
+			return errWalkDone
+		}
+
+		err := fn(full, entry, depth)
+		switch {
+		case errors.Is(err, fs.SkipDir):
+			continue
+		case err != nil:
+			return err
+		}
+		if entry.IsDir() && (w.maxDepth == 0 || depth < w.maxDepth) {
+			if err := w.walkDir(full, depth+1, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ignored reports whether the rules in effect exclude a path. The last rule that matches decides, so deeper .gitignore files override outer ones.
+func (w *treeWalker) ignored(full string, isDir bool) bool {
+	ignored := false
+	for _, rule := range w.rules {
+		if rule.matches(full, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matches reports whether a rule matches a path.
+func (r ignoreRule) matches(full string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	rel, err := filepath.Rel(r.dir, full)
+	if err != nil || !isWithin(r.dir, full) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if r.anchored {
+		return matchGlob(r.pattern, rel)
+	}
+	return matchGlob(r.pattern, path.Base(rel))
+}
+
+// readIgnoreFile returns the rules of the .gitignore file in dir, if there is one.
+func readIgnoreFile(dir string) []ignoreRule {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{dir: dir}
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			rule.negate, line = true, rest
+		}
+		line = strings.TrimPrefix(line, `\`) // Escapes a leading "#" or "!"
+		if rest, ok := strings.CutSuffix(line, "/"); ok {
+			rule.dirOnly, line = true, rest
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if rule.pattern == "" || validateGlob(rule.pattern) != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// resolveDir resolves the path argument of a tool that walks a tree, defaulting to the workspace, and checks it exists.
+// It also returns the workspace, where .gitignore files start to apply.
+func (e *ToolEnv) resolveDir(ctx context.Context, dir string) (string, string, error) {
+	if strings.TrimSpace(dir) == "" {
+		dir = "."
+	}
+	full, err := e.validatePath(ctx, dir)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := os.Stat(full); err != nil {
+		return "", "", fmt.Errorf("accessing %s: %w", dir, err)
+	}
+	workDir, err := e.workDir(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return full, workDir, nil
+}
+
+// displayPath shows a path the way tools accept it: relative to the workspace, under ~/ for the home directory, or absolute.
+func displayPath(workDir, full string) string {
+	if isWithin(workDir, full) {
+		rel, _ := filepath.Rel(workDir, full)
+		return filepath.ToSlash(rel)
+	}
+	if home, err := os.UserHomeDir(); err == nil && isWithin(home, full) && full != home {
+		rel, _ := filepath.Rel(home, full)
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return full
+}
+
+// page tracks which results of a search belong on the requested page.
+type page struct {
+	offset, limit int
+	seen          int  // Results counted so far, including those before the page
+	shown         int  // Results on the page
+	more          bool // A result past the page was found, so the search can stop
+}
+
+// newPage returns a page starting after offset results, holding limit results: defaultLimit when unset and at most maxLimit.
+func newPage(offset, limit, defaultLimit, maxLimit int) *page {
+	switch {
+	case limit <= 0:
+		limit = defaultLimit
+	case limit > maxLimit:
+		limit = maxLimit
+	}
+	return &page{offset: max(offset, 0), limit: limit}
+}
+
+// next counts a result and reports whether it belongs on the page.
+func (p *page) next() bool {
+	p.seen++
+	switch {
+	case p.seen <= p.offset:
+		return false
+	case p.seen <= p.offset+p.limit:
+		p.shown++
+		return true
+	default:
+		p.more = true
+		return false
+	}
+}
+
+// footer describes which results were shown and how to get more, or is empty when everything fit on one page.
+func (p *page) footer(what string, w *treeWalker) string {
+	var notes []string
+	switch {
+	case p.more:
+		notes = append(notes, fmt.Sprintf("%s %d-%d shown; more remain, call again with offset=%d", what, p.offset+1, p.offset+p.shown, p.offset+p.shown))
+	case p.offset > 0:
+		notes = append(notes, fmt.Sprintf("%s %d-%d of %d shown", what, p.offset+1, p.offset+p.shown, p.seen))
+	}
+	if w != nil && w.stopped {
+		notes = append(notes, fmt.Sprintf("stopped after scanning %d entries; narrow the path or pattern", maxWalkEntries))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return "\n[" + strings.Join(notes, "; ") + "]"
+}
@@ -0,0 +1,27 @@
+package users
+
+import "time"
+
+// Roles, from most to least privileged.
+const (
+	RoleAdmin    Role = "admin"     // Every tool plus user management
+	RoleOperator Role = "operator"  // Every tool
+	RoleReadOnly Role = "read-only" // Only tools that don't change anything
+)
+
+const (
+	inviteTTL         = 24 * time.Hour
+	inviteCodeBytes   = 6
+	userWorkspaceDir  = "users"
+	userNamespaceName = "user-%d"
+)
+
+// readOnlyTools are the tools available to read-only users.
+var readOnlyTools = map[string]bool{
+	"read_file":  true,
+	"send_file":  true,
+	"view_image": true,
+	"list_dir":   true,
+	"glob":       true,
+	"grep":       true,
+}
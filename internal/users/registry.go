@@ -0,0 +1,276 @@
+package users
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidInvite is returned when an invite code is unknown or expired.
+var ErrInvalidInvite = errors.New("invite code is invalid or expired")
+
+// Open loads the registry from path, starting empty if the file doesn't exist yet.
+func Open(path string) (*Registry, error) {
+	r := &Registry{
+		path:    path,
+		users:   make(map[int64]User),
+		invites: make(map[string]Invite),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read user registry: %w", err)
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse user registry %s: %w", path, err)
+	}
+	for _, u := range file.Users {
+		r.users[u.ID] = u
+	}
+	for _, inv := range file.Invites {
+		r.invites[inv.Code] = inv
+	}
+
+	slog.Info("user registry loaded", "path", path, "users", len(r.users))
+	return r, nil
+}
+
+// ParseRole parses a role name, accepting "readonly" as an alias of read-only.
+func ParseRole(name string) (Role, error) {
+	switch Role(strings.ToLower(strings.TrimSpace(name))) {
+	case RoleAdmin:
+		return RoleAdmin, nil
+	case RoleOperator:
+		return RoleOperator, nil
+	case RoleReadOnly, "readonly":
+		return RoleReadOnly, nil
+	default:
+		return "", fmt.Errorf("unknown role %q (use %s, %s or %s)", name, RoleAdmin, RoleOperator, RoleReadOnly)
+	}
+}
+
+// CanUseTool reports whether the role may call a tool.
+func (r Role) CanUseTool(name string) bool {
+	switch r {
+	case RoleAdmin, RoleOperator:
+		return true
+	case RoleReadOnly:
+		return readOnlyTools[name]
+	default:
+		return false
+	}
+}
+
+// Get returns the user with the given Telegram ID.
+func (r *Registry) Get(id int64) (User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[id]
+	return u, ok
+}
+
+// List returns all users, admins first, then by ID.
+func (r *Registry) List() []User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]User, 0, len(r.users))
+	for _, u := range r.users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if rank(list[i].Role) != rank(list[j].Role) {
+			return rank(list[i].Role) < rank(list[j].Role)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// HasAdmin reports whether at least one admin is registered.
+func (r *Registry) HasAdmin() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.adminCount() > 0
+}
+
+// Bootstrap registers the owner of an instance as admin. The owner keeps the shared memory and workspace root
+// that existed before users were introduced. It is a no-op if the user is already registered.
+func (r *Registry) Bootstrap(id int64, username string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+
+	u := User{ID: id, Username: username, Role: RoleAdmin, AddedAt: time.Now()}
+	r.users[id] = u
+	if err := r.save(); err != nil {
+		delete(r.users, id)
+		return User{}, err
+	}
+
+	slog.Info("registered instance owner as admin", "user_id", id, "username", username)
+	return u, nil
+}
+
+// CreateInvite creates a one-time invite code for the given role.
+func (r *Registry) CreateInvite(role Role, createdBy int64) (Invite, error) {
+	buf := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return Invite{}, fmt.Errorf("generate invite code: %w", err)
+	}
+
+	inv := Invite{
+		Code:      hex.EncodeToString(buf),
+		Role:      role,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(inviteTTL),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneInvites()
+	r.invites[inv.Code] = inv
+	if err := r.save(); err != nil {
+		delete(r.invites, inv.Code)
+		return Invite{}, err
+	}
+	return inv, nil
+}
+
+// Redeem registers a Telegram user with the role of an invite and consumes the invite.
+// New users get their own memory namespace and workspace subdirectory.
+func (r *Registry) Redeem(code string, id int64, username string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.invites[strings.TrimSpace(code)]
+	if !ok || time.Now().After(inv.ExpiresAt) {
+		return User{}, ErrInvalidInvite
+	}
+	if _, exists := r.users[id]; exists {
+		return User{}, fmt.Errorf("you are already registered")
+	}
+
+	u := User{
+		ID:        id,
+		Username:  username,
+		Role:      inv.Role,
+		Namespace: fmt.Sprintf(userNamespaceName, id),
+		Workspace: filepath.Join(userWorkspaceDir, fmt.Sprint(id)),
+		AddedBy:   inv.CreatedBy,
+		AddedAt:   time.Now(),
+	}
+	r.users[id] = u
+	delete(r.invites, inv.Code)
+	if err := r.save(); err != nil {
+		delete(r.users, id)
+		r.invites[inv.Code] = inv
+		return User{}, err
+	}
+
+	slog.Info("user joined", "user_id", id, "username", username, "role", u.Role, "invited_by", inv.CreatedBy)
+	return u, nil
+}
+
+// Revoke removes a user. The last admin cannot be removed.
+func (r *Registry) Revoke(id int64) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return User{}, fmt.Errorf("user %d is not registered", id)
+	}
+	if u.Role == RoleAdmin && r.adminCount() == 1 {
+		return User{}, fmt.Errorf("cannot revoke the last admin")
+	}
+
+	delete(r.users, id)
+	if err := r.save(); err != nil {
+		r.users[id] = u
+		return User{}, err
+	}
+
+	slog.Info("user revoked", "user_id", id, "username", u.Username)
+	return u, nil
+}
+
+// adminCount counts registered admins. The caller must hold the lock.
+func (r *Registry) adminCount() int {
+	n := 0
+	for _, u := range r.users {
+		if u.Role == RoleAdmin {
+			n++
+		}
+	}
+	return n
+}
+
+// pruneInvites drops expired invites. The caller must hold the write lock.
+func (r *Registry) pruneInvites() {
+	now := time.Now()
+	for code, inv := range r.invites {
+		if now.After(inv.ExpiresAt) {
+			delete(r.invites, code)
+		}
+	}
+}
+
+// save writes the registry atomically with owner-only permissions. The caller must hold the write lock.
+func (r *Registry) save() error {
+	file := registryFile{Users: make([]User, 0, len(r.users))}
+	for _, u := range r.users {
+		file.Users = append(file.Users, u)
+	}
+	sort.Slice(file.Users, func(i, j int) bool { return file.Users[i].ID < file.Users[j].ID })
+	for _, inv := range r.invites {
+		file.Invites = append(file.Invites, inv)
+	}
+	sort.Slice(file.Invites, func(i, j int) bool { return file.Invites[i].Code < file.Invites[j].Code })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode user registry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("create registry directory: %w", err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write user registry: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("write user registry: %w", err)
+	}
+	return nil
+}
+
+// rank orders roles for listing.
+func rank(role Role) int {
+	switch role {
+	case RoleAdmin:
+		return 0
+	case RoleOperator:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,42 @@
+package users
+
+import (
+	"sync"
+	"time"
+)
+
+// Role determines which tools and commands a user may use.
+type Role string
+
+// User is a person allowed to talk to the bot, identified by their immutable Telegram user ID.
+type User struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username,omitempty"` // Last known username, for display only
+	Role      Role      `json:"role"`
+	Namespace string    `json:"namespace,omitempty"` // Memory namespace; empty is the shared memory
+	Workspace string    `json:"workspace,omitempty"` // Workspace subdirectory; empty is the workspace root
+	AddedBy   int64     `json:"added_by,omitempty"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// Invite is a one-time code an admin hands out to let someone join with a role.
+type Invite struct {
+	Code      string    `json:"code"`
+	Role      Role      `json:"role"`
+	CreatedBy int64     `json:"created_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Registry stores the users of a Vayuu instance and pending invites in a JSON file.
+type Registry struct {
+	path    string
+	mu      sync.RWMutex
+	users   map[int64]User
+	invites map[string]Invite
+}
+
+// registryFile is the on-disk format of the registry.
+type registryFile struct {
+	Users   []User   `json:"users"`
+	Invites []Invite `json:"invites,omitempty"`
+}